@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// auth0TokenResponse is the body of a response from Auth0's /oauth/token
+type auth0TokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Scope            string `json:"scope"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// RefreshAuth0Tokens exchanges the refresh token for new tokens with the
+// refresh_token grant at the tenant's /oauth/token endpoint. With rotating
+// refresh tokens the response carries a new refresh token and the old one is
+// spent, so the returned tokens must replace the stored ones.
+func RefreshAuth0Tokens(client *http.Client, tokens *Auth0Tokens) (*Auth0Tokens, error) {
+	if !tokens.CanRefresh() {
+		return nil, fmt.Errorf("no refresh token, client id or token endpoint to refresh with")
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {tokens.ClientID},
+		"refresh_token": {tokens.RefreshToken},
+	}
+	resp, err := client.PostForm(tokens.TokenURL, form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body auth0TokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse token response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error != "" {
+			return nil, fmt.Errorf("token refresh rejected: %s: %s", body.Error, body.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token refresh rejected: %s", resp.Status)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+
+	refreshed := *tokens
+	refreshed.AccessToken = body.AccessToken
+	if body.RefreshToken != "" {
+		refreshed.RefreshToken = body.RefreshToken
+	}
+	if body.Scope != "" {
+		refreshed.Scope = body.Scope
+	}
+	refreshed.ExpiresAt = time.Time{}
+	if body.ExpiresIn > 0 {
+		refreshed.ExpiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return &refreshed, nil
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAuth0Tokens(t *testing.T) {
+	accessToken := testJWT(`{"iss":"https://tenant.auth0.com/","aud":"https://api.example.com","exp":1893456000}`)
+	localStorage := map[string]string{
+		"@@auth0spajs@@::client123::@@user@@": `{"id_token":"x","decodedToken":{"claims":{"iss":"https://login.example.com/"}}}`,
+		"@@auth0spajs@@::client123::https://api.example.com::openid profile offline_access": `{
+			"body": {
+				"client_id": "client123",
+				"access_token": "` + accessToken + `",
+				"refresh_token": "v1.refresh-1",
+				"expires_in": 86400,
+				"token_type": "Bearer",
+				"audience": "https://api.example.com",
+				"scope": "openid profile offline_access"
+			},
+			"expiresAt": 1740600000
+		}`,
+	}
+
+	tokens, err := ParseAuth0Tokens(localStorage)
+	if err != nil {
+		t.Fatalf("ParseAuth0Tokens failed: %v", err)
+	}
+	if tokens.AccessToken != accessToken || tokens.RefreshToken != "v1.refresh-1" || tokens.ClientID != "client123" {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	if tokens.Audience != "https://api.example.com" || tokens.Scope != "openid profile offline_access" {
+		t.Errorf("unexpected audience/scope %+v", tokens)
+	}
+	if tokens.ExpiresAt.Unix() != 1740600000 {
+		t.Errorf("ExpiresAt = %v, want the cache's expiresAt", tokens.ExpiresAt)
+	}
+	if tokens.TokenURL != "https://tenant.auth0.com/oauth/token" {
+		t.Errorf("TokenURL = %q, want the access token's issuer", tokens.TokenURL)
+	}
+}
+
+func TestParseAuth0Tokens_OpaqueTokenUsesIDTokenIssuer(t *testing.T) {
+	localStorage := map[string]string{
+		"@@auth0spajs@@::client123::@@user@@":        `{"decodedToken":{"claims":{"iss":"https://login.example.com/"}}}`,
+		"@@auth0spajs@@::client123::default::openid": `{"body":{"access_token":"opaque","refresh_token":"r"},"expiresAt":1}`,
+	}
+
+	tokens, err := ParseAuth0Tokens(localStorage)
+	if err != nil {
+		t.Fatalf("ParseAuth0Tokens failed: %v", err)
+	}
+	if tokens.ClientID != "client123" || tokens.Scope != "openid" {
+		t.Errorf("expected client and scope from the key, got %+v", tokens)
+	}
+	if tokens.TokenURL != "https://login.example.com/oauth/token" || !tokens.CanRefresh() {
+		t.Errorf("TokenURL = %q, CanRefresh = %v", tokens.TokenURL, tokens.CanRefresh())
+	}
+	if !tokens.Expired(0) {
+		t.Error("expected the token to be expired")
+	}
+}
+
+// fakeTokenEndpoint is a stand-in for Auth0's /oauth/token that rotates
+// refresh tokens: each one works once
+type fakeTokenEndpoint struct {
+	valid string // The refresh token that will be accepted
+	calls int
+}
+
+func (f *fakeTokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.calls++
+	r.ParseForm()
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("client_id") != "client123" || r.Form.Get("refresh_token") != f.valid {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "Unknown or invalid refresh token."})
+		return
+	}
+
+	f.valid = f.valid + "-rotated"
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token":  "new-access-token",
+		"refresh_token": f.valid,
+		"expires_in":    3600,
+		"token_type":    "Bearer",
+	})
+}
+
+func TestRefreshAuth0Tokens(t *testing.T) {
+	endpoint := &fakeTokenEndpoint{valid: "refresh-1"}
+	server := httptest.NewServer(endpoint)
+	defer server.Close()
+
+	tokens := &Auth0Tokens{AccessToken: "old", RefreshToken: "refresh-1", ClientID: "client123", Scope: "openid", TokenURL: server.URL}
+	refreshed, err := RefreshAuth0Tokens(server.Client(), tokens)
+	if err != nil {
+		t.Fatalf("RefreshAuth0Tokens failed: %v", err)
+	}
+	if refreshed.AccessToken != "new-access-token" || refreshed.RefreshToken != "refresh-1-rotated" || refreshed.Scope != "openid" {
+		t.Errorf("unexpected refreshed tokens %+v", refreshed)
+	}
+	if refreshed.Expired(time.Minute) {
+		t.Errorf("ExpiresAt = %v, want about an hour from now", refreshed.ExpiresAt)
+	}
+
+	// The spent refresh token is rejected
+	_, err = RefreshAuth0Tokens(server.Client(), tokens)
+	if err == nil || !strings.Contains(err.Error(), "invalid_grant") {
+		t.Errorf("expected reuse of the old refresh token to fail, got %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// BmuxSession is a browser launched by the bmux CLI, as recorded in
+// ~/.bmux/sessions.json. Sessions are usually named after the browser
+// (edge, chrome, ...), but can have any name (beta, work, ...).
+type BmuxSession struct {
+	Name      string `json:"-"`
+	Type      string `json:"type"` // "cdp" or "webdriver"; empty in old state files, which only held CDP sessions
+	Port      int    `json:"port"`
+	PID       int    `json:"pid"`
+	Bin       string `json:"bin"`
+	SessionID string `json:"session_id,omitempty"` // WebDriver session
+}
+
+// IsCDP reports whether the session is a browser with a CDP debug port
+func (s *BmuxSession) IsCDP() bool {
+	return s.Type == "" || s.Type == "cdp"
+}
+
+// Alive reports whether the session's browser is still running, the same
+// way bmux's prune_stale decides. Under WSL the recorded PID is the interop
+// shim, so CDP sessions are probed on their debug port instead.
+func (s *BmuxSession) Alive() bool {
+	if IsWSL() && s.IsCDP() {
+		config := &BrowserConfig{DebugPort: s.Port}
+		applyWSLBridge(config)
+		return debugEndpointResponds(config.DebugURL())
+	}
+	return processAlive(s.PID)
+}
+
+// BmuxStore reads the session state of the bmux CLI, so fetch can attach to
+// the browsers bmux launched instead of starting a second one
+type BmuxStore struct {
+	dir string // bmux state directory (e.g., ~/.bmux)
+}
+
+// NewBmuxStore creates a BmuxStore for bmux's default state directory
+func NewBmuxStore() (*BmuxStore, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return &BmuxStore{dir: filepath.Join(homeDir, ".bmux")}, nil
+}
+
+// load reads the raw session entries, keeping fields fetch does not know
+// so they survive a prune. Returns an empty map if bmux has no state.
+func (s *BmuxStore) load() (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, "sessions.json"))
+	if os.IsNotExist(err) {
+		return map[string]json.RawMessage{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bmux sessions: %w", err)
+	}
+
+	raw := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse bmux sessions: %w", err)
+	}
+	return raw, nil
+}
+
+// Sessions returns all recorded bmux sessions sorted by name
+func (s *BmuxStore) Sessions() ([]*BmuxSession, error) {
+	raw, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]*BmuxSession, 0, len(raw))
+	for name, entry := range raw {
+		session := &BmuxSession{}
+		if err := json.Unmarshal(entry, session); err != nil {
+			return nil, fmt.Errorf("failed to parse bmux session %q: %w", name, err)
+		}
+		session.Name = name
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Name < sessions[j].Name })
+	return sessions, nil
+}
+
+// Prune removes sessions whose browsers are no longer running, as bmux's
+// prune_stale does. Sessions without a PID are kept.
+// Returns the number of sessions removed.
+func (s *BmuxStore) Prune() (int, error) {
+	raw, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for name, entry := range raw {
+		session := &BmuxSession{}
+		if err := json.Unmarshal(entry, session); err != nil || session.PID == 0 || session.Alive() {
+			continue
+		}
+		delete(raw, name)
+		pruned++
+	}
+
+	if pruned > 0 {
+		data, err := json.Marshal(raw)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal bmux sessions: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(s.dir, "sessions.json"), data, 0644); err != nil {
+			return 0, fmt.Errorf("failed to write bmux sessions: %w", err)
+		}
+	}
+	return pruned, nil
+}
+
+// bmuxAttachedPattern takes the session name from an attached target, which
+// is "edge", "edge:3" (tab index) or "edge=TARGETID"
+var bmuxAttachedPattern = regexp.MustCompile(`^\w+`)
+
+// Attached returns the name of the session bmux is attached to, or "" when
+// it is detached
+func (s *BmuxStore) Attached() string {
+	data, err := os.ReadFile(filepath.Join(s.dir, "attached"))
+	if err != nil {
+		return ""
+	}
+	return bmuxAttachedPattern.FindString(strings.TrimSpace(string(data)))
+}
+
+// Session returns the named live session. A stale session is pruned and
+// reported as an error.
+func (s *BmuxStore) Session(name string) (*BmuxSession, error) {
+	sessions, err := s.Sessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, session := range sessions {
+		if session.Name != name {
+			names = append(names, session.Name)
+			continue
+		}
+		if session.PID != 0 && !session.Alive() {
+			_, _ = s.Prune()
+			return nil, fmt.Errorf("bmux session %q is stale: pid %d is no longer running", name, session.PID)
+		}
+		return session, nil
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no bmux session named %q (bmux has no sessions)", name)
+	}
+	return nil, fmt.Errorf("no bmux session named %q (available: %s)", name, strings.Join(names, ", "))
+}
+
+// BrowserType returns the browser in the registry a session runs: the entry
+// whose executable is the session's binary, or else the browser the session
+// is named after
+func (s *BmuxSession) BrowserType(registry *BrowserRegistry) (BrowserType, error) {
+	return s.browserType(registry, hostDiscoveryEnv())
+}
+
+func (s *BmuxSession) browserType(registry *BrowserRegistry, env discoveryEnv) (BrowserType, error) {
+	if s.Bin != "" {
+		var match BrowserType
+		for _, name := range registry.Names() {
+			def, err := registry.Lookup(BrowserType(name))
+			if err != nil || filepath.Clean(env.browserConfig(def).ExePath) != filepath.Clean(s.Bin) {
+				continue
+			}
+			// Several entries may share a binary; the session's name wins
+			if match == "" || name == s.Name {
+				match = BrowserType(name)
+			}
+		}
+		if match != "" {
+			return match, nil
+		}
+	}
+
+	if _, err := registry.Lookup(BrowserType(s.Name)); err == nil {
+		return BrowserType(strings.ToLower(s.Name)), nil
+	}
+	return "", fmt.Errorf("cannot tell which browser bmux session %q runs (%s); select it with --browser", s.Name, s.Bin)
+}
+
+// BmuxSessionBrowser returns the browser type of the named live bmux session
+func BmuxSessionBrowser(name string) (BrowserType, error) {
+	store, err := NewBmuxStore()
+	if err != nil {
+		return "", err
+	}
+	session, err := store.Session(name)
+	if err != nil {
+		return "", err
+	}
+	registry, err := LoadBrowserRegistry()
+	if err != nil {
+		return "", err
+	}
+	return session.BrowserType(registry)
+}
+
+// Find returns the live CDP session running the given browser, or nil. The
+// session bmux is attached to is preferred; otherwise a session is matched
+// by name or by its browser binary. Stale sessions are pruned first.
+func (s *BmuxStore) Find(config *BrowserConfig) (*BmuxSession, error) {
+	if _, err := s.Prune(); err != nil {
+		return nil, err
+	}
+	sessions, err := s.Sessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var match *BmuxSession
+	attached := s.Attached()
+	for _, session := range sessions {
+		if !session.IsCDP() || (session.Name != string(config.Type) && session.Bin != config.ExePath) {
+			continue
+		}
+		if session.Name == attached {
+			return session, nil
+		}
+		if match == nil {
+			match = session
+		}
+	}
+	return match, nil
+}
+
+// resolveBmuxSession finds the bmux session to connect to: the one named by
+// config.BmuxSession, or else a live session of the configured browser.
+// Returns nil when bmux has no suitable session. Unreadable bmux state only
+// fails an explicit request.
+func resolveBmuxSession(config *BrowserConfig) (*BmuxSession, error) {
+	store, err := NewBmuxStore()
+	if err != nil {
+		return nil, err
+	}
+
+	if config.BmuxSession == "" {
+		session, _ := store.Find(config)
+		return session, nil
+	}
+
+	session, err := store.Session(config.BmuxSession)
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsCDP() {
+		return nil, fmt.Errorf("bmux session %q is a WebDriver session; fetch can only attach to CDP sessions", session.Name)
+	}
+	return session, nil
+}
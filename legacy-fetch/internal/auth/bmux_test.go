@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeBmuxState creates a bmux state dir with the given sessions.json and
+// attached contents
+func writeBmuxState(t *testing.T, sessions, attached string) *BmuxStore {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sessions.json"), []byte(sessions), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if attached != "" {
+		if err := os.WriteFile(filepath.Join(dir, "attached"), []byte(attached), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &BmuxStore{dir: dir}
+}
+
+func TestBmuxStore_Sessions(t *testing.T) {
+	store := writeBmuxState(t, `{"edge":{"port":9222,"pid":10,"bin":"/usr/bin/microsoft-edge"},"safari":{"type":"webdriver","port":4444,"pid":11,"session_id":"abc"}}`, "")
+
+	sessions, err := store.Sessions()
+	if err != nil {
+		t.Fatalf("Sessions failed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].Name != "edge" || sessions[1].Name != "safari" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+	if !sessions[0].IsCDP() || sessions[0].Port != 9222 || sessions[0].Bin != "/usr/bin/microsoft-edge" {
+		t.Errorf("legacy session without type should be CDP: %+v", sessions[0])
+	}
+	if sessions[1].IsCDP() || sessions[1].SessionID != "abc" {
+		t.Errorf("unexpected WebDriver session: %+v", sessions[1])
+	}
+
+	missing := &BmuxStore{dir: t.TempDir()}
+	if sessions, err := missing.Sessions(); err != nil || len(sessions) != 0 {
+		t.Errorf("expected no sessions without state, got %v, %v", sessions, err)
+	}
+}
+
+func TestBmuxStore_Attached(t *testing.T) {
+	for _, attached := range []string{"edge", "edge:3", "edge=9F2A6C\n"} {
+		store := writeBmuxState(t, "{}", attached)
+		if got := store.Attached(); got != "edge" {
+			t.Errorf("Attached() with %q = %q, want edge", attached, got)
+		}
+	}
+
+	if got := writeBmuxState(t, "{}", "").Attached(); got != "" {
+		t.Errorf("Attached() when detached = %q", got)
+	}
+}
+
+func TestBmuxStore_Prune(t *testing.T) {
+	store := writeBmuxState(t, fmt.Sprintf(
+		`{"edge":{"type":"cdp","port":9222,"pid":%d,"extra":1},"chrome":{"type":"cdp","port":9223,"pid":999999999},"brave":{"port":9224}}`,
+		os.Getpid()), "")
+
+	pruned, err := store.Prune()
+	if err != nil || pruned != 1 {
+		t.Fatalf("Prune = %d, %v; want 1", pruned, err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(store.dir, "sessions.json"))
+	if strings.Contains(string(data), "chrome") || !strings.Contains(string(data), `"extra":1`) || !strings.Contains(string(data), "brave") {
+		t.Errorf("unexpected sessions after prune: %s", data)
+	}
+}
+
+func TestBmuxStore_Session(t *testing.T) {
+	store := writeBmuxState(t, fmt.Sprintf(
+		`{"edge":{"type":"cdp","port":9222,"pid":%d},"chrome":{"type":"cdp","port":9223,"pid":999999999}}`,
+		os.Getpid()), "")
+
+	if session, err := store.Session("edge"); err != nil || session.Port != 9222 {
+		t.Errorf("Session(edge) = %+v, %v", session, err)
+	}
+	if _, err := store.Session("chrome"); err == nil || !strings.Contains(err.Error(), "stale") {
+		t.Errorf("expected stale session error, got %v", err)
+	}
+	if _, err := store.Session("brave"); err == nil || !strings.Contains(err.Error(), "available: edge") {
+		t.Errorf("expected unknown session error listing edge, got %v", err)
+	}
+}
+
+func TestBmuxStore_Find(t *testing.T) {
+	pid := os.Getpid()
+	sessions := fmt.Sprintf(`{
+		"edge":{"type":"cdp","port":9222,"pid":%d,"bin":"/usr/bin/microsoft-edge"},
+		"beta":{"type":"cdp","port":9223,"pid":%d,"bin":"/usr/bin/microsoft-edge"},
+		"safari":{"type":"webdriver","port":4444,"pid":%d}
+	}`, pid, pid, pid)
+	edge := &BrowserConfig{Type: BrowserEdge, ExePath: "/usr/bin/microsoft-edge"}
+
+	session, err := writeBmuxState(t, sessions, "").Find(edge)
+	if err != nil || session == nil || session.Name != "beta" {
+		t.Errorf("expected the first matching session, got %+v, %v", session, err)
+	}
+
+	session, err = writeBmuxState(t, sessions, "edge:2").Find(edge)
+	if err != nil || session == nil || session.Name != "edge" {
+		t.Errorf("expected the attached session, got %+v, %v", session, err)
+	}
+
+	session, err = writeBmuxState(t, sessions, "safari").Find(&BrowserConfig{Type: BrowserSafari})
+	if err != nil || session != nil {
+		t.Errorf("expected WebDriver sessions to be skipped, got %+v, %v", session, err)
+	}
+}
+
+func TestBmuxSession_BrowserType(t *testing.T) {
+	env := fakeDiscoveryEnv("linux", map[string]string{
+		"microsoft-edge-stable": "/usr/bin/microsoft-edge-stable",
+		"google-chrome":         "/usr/bin/google-chrome",
+	})
+	registry := NewBrowserRegistry()
+
+	tests := []struct {
+		session BmuxSession
+		want    BrowserType
+	}{
+		{BmuxSession{Name: "beta", Bin: "/usr/bin/microsoft-edge-stable"}, BrowserEdge},
+		{BmuxSession{Name: "work", Bin: "/usr/bin/google-chrome"}, BrowserChrome},
+		{BmuxSession{Name: "brave", Bin: "/opt/brave/brave"}, BrowserBrave},
+		{BmuxSession{Name: "edge"}, BrowserEdge},
+	}
+	for _, tt := range tests {
+		got, err := tt.session.browserType(registry, env)
+		if err != nil || got != tt.want {
+			t.Errorf("browserType(%s, %s) = %q, %v; want %q", tt.session.Name, tt.session.Bin, got, err, tt.want)
+		}
+	}
+
+	unknown := BmuxSession{Name: "work", Bin: "/opt/other/browser"}
+	if _, err := unknown.browserType(registry, env); err == nil || !strings.Contains(err.Error(), "--browser") {
+		t.Errorf("expected an error suggesting --browser, got %v", err)
+	}
+}
@@ -2,13 +2,11 @@ package auth
 
 import (
 	"bufio"
-	"context"
-	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
 
@@ -20,6 +18,16 @@ import (
 type BrowserAuth struct {
 	sessionManager *SessionManager
 	browserType    BrowserType
+	config         *BrowserConfig // Explicit configuration; built from browserType when nil
+	confirm        io.Reader      // Pressing Enter here completes the login early
+	progress       io.Writer      // Receives progress messages; os.Stdout when nil
+
+	// Override loginPollInterval/loginStableThreshold when set (tests)
+	pollInterval    time.Duration
+	stableThreshold time.Duration
+
+	// Replaces openDriver's browser connection when set (tests)
+	newDriver func(config *BrowserConfig) (BrowserDriver, error)
 }
 
 // NewBrowserAuth creates a new BrowserAuth instance
@@ -27,12 +35,53 @@ func NewBrowserAuth(sessionManager *SessionManager) *BrowserAuth {
 	return &BrowserAuth{
 		sessionManager: sessionManager,
 		browserType:    BrowserEdge, // Default to Edge for work SSO
+		confirm:        os.Stdin,
 	}
 }
 
 // SetBrowserType sets which browser to use for authentication
 func (b *BrowserAuth) SetBrowserType(browserType BrowserType) {
 	b.browserType = browserType
+	b.config = nil
+}
+
+// SetBrowserConfig uses an explicit browser configuration for authentication,
+// e.g. one carrying a --cdp-url override
+func (b *BrowserAuth) SetBrowserConfig(config *BrowserConfig) {
+	b.browserType = config.Type
+	b.config = config
+}
+
+// SetProgress sends progress messages to w instead of stdout, e.g. so
+// stdout only holds a command's result
+func (b *BrowserAuth) SetProgress(w io.Writer) {
+	b.progress = w
+}
+
+// browserConfig returns a copy of the configuration to authenticate with
+func (b *BrowserAuth) browserConfig() (*BrowserConfig, error) {
+	var config *BrowserConfig
+	if b.config != nil {
+		copied := *b.config
+		config = &copied
+	} else {
+		var err error
+		if config, err = GetBrowserConfig(b.browserType); err != nil {
+			return nil, err
+		}
+	}
+	if b.progress != nil {
+		config.Progress = b.progress
+	}
+	return config, nil
+}
+
+// progressf prints a progress message to w, or to stdout when w is nil
+func progressf(w io.Writer, format string, args ...interface{}) {
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format, args...)
 }
 
 // Authenticate opens a browser to the target URL and captures cookies after login
@@ -45,91 +94,30 @@ func (b *BrowserAuth) Authenticate(targetURL string) error {
 	host := parsedURL.Host
 
 	// Get browser config
-	config, err := GetBrowserConfig(b.browserType)
+	config, err := b.browserConfig()
 	if err != nil {
 		return fmt.Errorf("failed to get browser config: %w", err)
 	}
 
-	fmt.Printf("Opening browser to: %s\n", targetURL)
-	fmt.Println("Completing login flow...")
+	progressf(b.progress, "Opening browser to: %s\n", targetURL)
+	progressf(b.progress, "Completing login flow...\n")
 
 	// Try to connect to existing browser, or launch one
-	browser, needsClose, err := b.getOrLaunchBrowser(config)
+	driver, err := b.openDriver(config)
 	if err != nil {
 		return fmt.Errorf("failed to get browser: %w", err)
 	}
-	if needsClose {
-		defer browser.MustClose()
-	}
+	defer driver.Close()
 
-	// Create a new page and navigate to target URL
-	page := browser.MustPage(targetURL)
-	defer page.MustClose()
-
-	// Wait for login completion
-	originalHost := host
-	loginComplete := make(chan bool, 1)
-	done := make(chan struct{})
-
-	// Goroutine 1: Watch for URL to stabilize on original host
-	go func() {
-		var lastURL string
-		var stableTime time.Time
-		const stableThreshold = 3 * time.Second
-
-		for {
-			select {
-			case <-done:
-				return
-			default:
-				time.Sleep(500 * time.Millisecond)
-				info, err := page.Info()
-				if err != nil {
-					return
-				}
-
-				currentURL := info.URL
-				currentParsed, err := url.Parse(currentURL)
-				if err != nil {
-					continue
-				}
-
-				if currentURL != lastURL {
-					lastURL = currentURL
-					stableTime = time.Now()
-					continue
-				}
-
-				if currentParsed.Host == originalHost && time.Since(stableTime) >= stableThreshold {
-					select {
-					case loginComplete <- true:
-					default:
-					}
-					return
-				}
-			}
-		}
-	}()
-
-	// Goroutine 2: Wait for user to press Enter
-	go func() {
-		reader := bufio.NewReader(os.Stdin)
-		_, err := reader.ReadString('\n')
-		if err != nil {
-			return
-		}
-		select {
-		case loginComplete <- true:
-		default:
-		}
-	}()
+	if err := openPage(driver, targetURL, config.ReuseTab, b.progress); err != nil {
+		return err
+	}
 
-	<-loginComplete
-	close(done)
+	b.waitForLogin(driver, host, false, b.confirmEnter())
 
-	fmt.Println("Login completed. Capturing cookies...")
+	progressf(b.progress, "Login completed. Capturing cookies...\n")
 
-	cookies, err := b.extractCookies(browser, host)
+	cookies, err := driver.Cookies()
 	if err != nil {
 		return fmt.Errorf("failed to extract cookies: %w", err)
 	}
@@ -138,21 +126,24 @@ func (b *BrowserAuth) Authenticate(targetURL string) error {
 		return fmt.Errorf("no cookies captured - login may have failed")
 	}
 
-	fmt.Printf("Captured %d cookies\n", len(cookies))
+	progressf(b.progress, "Captured %d cookies\n", len(cookies))
 
 	if err := b.sessionManager.SaveCookies(host, cookies); err != nil {
 		return fmt.Errorf("failed to save cookies: %w", err)
 	}
 
-	fmt.Printf("Session saved for host: %s\n", host)
+	progressf(b.progress, "Session saved for host: %s\n", host)
 
 	return nil
 }
 
 // AuthResult contains everything captured during a browser authentication flow.
 type AuthResult struct {
-	Cookies      []*http.Cookie
-	LocalStorage map[string]string
+	Cookies        []*http.Cookie
+	LocalStorage   map[string]string
+	SessionStorage map[string]string // Only read from the page the login ends on
+	IndexedDB      map[string]string // Firebase Auth's firebaseLocalStorageDb records, by fbase_key
+	Headers        http.Header       // Credential headers the page sent, e.g. Authorization; CDP only
 }
 
 // AuthenticateAndCapture performs the browser auth flow and returns all captured
@@ -166,68 +157,180 @@ func (b *BrowserAuth) AuthenticateAndCapture(targetURL string) (*AuthResult, err
 
 	host := parsedURL.Host
 
-	config, err := GetBrowserConfig(b.browserType)
+	config, err := b.browserConfig()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get browser config: %w", err)
 	}
 
-	fmt.Printf("Opening browser to: %s\n", targetURL)
-	fmt.Println("Completing login flow...")
+	progressf(b.progress, "Opening browser to: %s\n", targetURL)
+	progressf(b.progress, "Completing login flow...\n")
 
-	browser, needsClose, err := b.getOrLaunchBrowser(config)
+	driver, err := b.openDriver(config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get browser: %w", err)
 	}
-	if needsClose {
-		defer browser.MustClose()
+	defer driver.Close()
+
+	if err := openPage(driver, targetURL, config.ReuseTab, b.progress); err != nil {
+		return nil, err
 	}
 
-	page := browser.MustPage(targetURL)
-	defer page.MustClose()
+	// Record the credentials the app sends, for providers without a known cache
+	stopWatching := func() http.Header { return nil }
+	if watcher, ok := driver.(interface{ WatchHeaders() func() http.Header }); ok {
+		stopWatching = watcher.WatchHeaders()
+	}
 
-	// Wait for login completion
 	// For SPA apps with Auth0, we need to wait until the URL moves past /landing
 	// The flow is: /landing → Auth0 → MS SSO → Auth0 callback → /data-queue
-	// We detect completion when the URL is stable on the original host AND
-	// is not the landing page (indicating the auth flow completed)
-	originalHost := host
+	b.waitForLogin(driver, host, true, b.confirmEnter())
+
+	progressf(b.progress, "Login completed. Capturing credentials...\n")
+	headers := stopWatching()
+
+	// Extract cookies
+	cookies, err := driver.Cookies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract cookies: %w", err)
+	}
+	progressf(b.progress, "Captured %d cookies\n", len(cookies))
+
+	// An explicit origin is read wherever it is loaded; it must be found
+	if config.StorageOrigin != "" {
+		progressf(b.progress, "Reading storage for origin: %s\n", config.StorageOrigin)
+		storage, err := readOriginStorage(driver, config.StorageOrigin)
+		if err != nil {
+			return nil, err
+		}
+		localStorage, err := storage.LocalStorage()
+		if err != nil {
+			return nil, err
+		}
+		progressf(b.progress, "Captured %d localStorage entries\n", len(localStorage))
+
+		sessionStorage, err := storage.SessionStorage()
+		if err != nil {
+			progressf(b.progress, "Warning: could not read sessionStorage: %v\n", err)
+			sessionStorage = map[string]string{}
+		} else if len(sessionStorage) > 0 {
+			progressf(b.progress, "Captured %d sessionStorage entries\n", len(sessionStorage))
+		}
+
+		indexedDB, err := storage.FirebaseIndexedDB()
+		if err != nil {
+			progressf(b.progress, "Warning: could not read IndexedDB: %v\n", err)
+			indexedDB = map[string]string{}
+		} else if len(indexedDB) > 0 {
+			progressf(b.progress, "Captured %d Firebase IndexedDB records\n", len(indexedDB))
+		}
+
+		return &AuthResult{
+			Cookies:        cookies,
+			LocalStorage:   localStorage,
+			SessionStorage: sessionStorage,
+			IndexedDB:      indexedDB,
+			Headers:        headers,
+		}, nil
+	}
+
+	// Extract localStorage from the page we're on
+	if currentURL, err := driver.URL(); err == nil {
+		progressf(b.progress, "Reading localStorage from: %s\n", currentURL)
+	}
+
+	localStorage, err := readLocalStorage(driver)
+	if err != nil {
+		// Non-fatal — some sites don't use localStorage
+		progressf(b.progress, "Warning: could not read localStorage: %v\n", err)
+		localStorage = map[string]string{}
+	} else {
+		progressf(b.progress, "Captured %d localStorage entries\n", len(localStorage))
+		if lister, ok := driver.(interface{ PageURLs() ([]string, error) }); ok && len(localStorage) == 0 {
+			// Debug: list all pages to see if we're on the wrong one
+			urls, _ := lister.PageURLs()
+			progressf(b.progress, "Browser has %d pages:\n", len(urls))
+			for i, u := range urls {
+				progressf(b.progress, "  [%d] %s\n", i, u)
+			}
+		}
+	}
+
+	sessionStorage, err := readSessionStorage(driver)
+	if err != nil {
+		progressf(b.progress, "Warning: could not read sessionStorage: %v\n", err)
+		sessionStorage = map[string]string{}
+	} else if len(sessionStorage) > 0 {
+		progressf(b.progress, "Captured %d sessionStorage entries\n", len(sessionStorage))
+	}
+
+	indexedDB, err := readFirebaseIndexedDB(driver)
+	if err != nil {
+		progressf(b.progress, "Warning: could not read IndexedDB: %v\n", err)
+		indexedDB = map[string]string{}
+	} else if len(indexedDB) > 0 {
+		progressf(b.progress, "Captured %d Firebase IndexedDB records\n", len(indexedDB))
+	}
+
+	return &AuthResult{
+		Cookies:        cookies,
+		LocalStorage:   localStorage,
+		SessionStorage: sessionStorage,
+		IndexedDB:      indexedDB,
+		Headers:        headers,
+	}, nil
+}
+
+// Login completion is detected by polling the page URL: the login is done once
+// the URL has stayed on the target host for stableThreshold with no login
+// popups open. Pressing Enter completes it immediately.
+const (
+	loginPollInterval    = 500 * time.Millisecond
+	loginStableThreshold = 3 * time.Second
+)
+
+// confirmEnter returns a channel that is closed when the user presses Enter
+// on b.confirm. It never closes when there is no confirm reader.
+func (b *BrowserAuth) confirmEnter() <-chan struct{} {
+	confirmed := make(chan struct{})
+	if b.confirm != nil {
+		go func() {
+			if _, err := bufio.NewReader(b.confirm).ReadString('\n'); err == nil {
+				close(confirmed)
+			}
+		}()
+	}
+	return confirmed
+}
+
+// waitForLogin blocks until the login flow has returned to host, or
+// confirmed is closed. With skipLanding, a URL on /landing does not count as
+// done, since SPAs park there before redirecting to the identity provider.
+func (b *BrowserAuth) waitForLogin(driver BrowserDriver, host string, skipLanding bool, confirmed <-chan struct{}) {
+	pollInterval, stableThreshold := loginPollInterval, loginStableThreshold
+	if b.pollInterval > 0 {
+		pollInterval, stableThreshold = b.pollInterval, b.stableThreshold
+	}
+
 	loginComplete := make(chan bool, 1)
 	done := make(chan struct{})
+	watching := make(chan struct{})
 
+	// Goroutine 1: Watch for URL to stabilize on original host
 	go func() {
-		var lastURL string
-		var stableTime time.Time
-		const stableThreshold = 3 * time.Second
+		defer close(watching)
+		tracker := &loginTracker{host: host, skipLanding: skipLanding, stableThreshold: stableThreshold, popups: popupWatcher{progress: b.progress}}
 
 		for {
 			select {
 			case <-done:
 				return
 			default:
-				time.Sleep(500 * time.Millisecond)
-				info, err := page.Info()
+				time.Sleep(pollInterval)
+				settled, err := tracker.poll(driver)
 				if err != nil {
 					return
 				}
-
-				currentURL := info.URL
-				currentParsed, err := url.Parse(currentURL)
-				if err != nil {
-					continue
-				}
-
-				if currentURL != lastURL {
-					lastURL = currentURL
-					stableTime = time.Now()
-					continue
-				}
-
-				// Must be on original host, stable, and NOT on /landing
-				isOnHost := currentParsed.Host == originalHost
-				isStable := time.Since(stableTime) >= stableThreshold
-				isPastLanding := currentParsed.Path != "/landing" && currentParsed.Path != "/landing/"
-
-				if isOnHost && isStable && isPastLanding {
+				if settled {
 					select {
 					case loginComplete <- true:
 					default:
@@ -238,167 +341,249 @@ func (b *BrowserAuth) AuthenticateAndCapture(targetURL string) (*AuthResult, err
 		}
 	}()
 
-	go func() {
-		reader := bufio.NewReader(os.Stdin)
-		_, err := reader.ReadString('\n')
-		if err != nil {
-			return
-		}
-		select {
-		case loginComplete <- true:
-		default:
-		}
-	}()
+	select {
+	case <-loginComplete:
+	case <-confirmed:
+	}
 
-	<-loginComplete
+	// Let the watcher finish its poll, which may have switched windows
 	close(done)
+	<-watching
+}
+
+// openPage opens the target URL in a new page. With reuse, a tab already
+// open on the target's origin is used instead when the driver can find one,
+// keeping the app's in-memory state and avoiding a fresh login.
+func openPage(driver BrowserDriver, targetURL string, reuse bool, w io.Writer) error {
+	if reuse {
+		if reuser, ok := driver.(interface{ Reuse(string) (bool, error) }); ok {
+			found, err := reuser.Reuse(targetURL)
+			if err != nil {
+				return err
+			}
+			if found {
+				current, _ := driver.URL()
+				progressf(w, "Reusing open tab: %s\n", current)
+				return nil
+			}
+		}
+		progressf(w, "No open tab on the target's origin; opening a new one\n")
+	}
+	return driver.Open(targetURL)
+}
 
-	fmt.Println("Login completed. Capturing credentials...")
+// loginTracker decides when a login has settled: the page URL has stayed on
+// the target host for stableThreshold with no login popups open
+type loginTracker struct {
+	host            string
+	skipLanding     bool
+	stableThreshold time.Duration
 
-	// Extract cookies
-	cookies, err := b.extractCookies(browser, host)
+	lastURL    string
+	stableTime time.Time
+	popups     popupWatcher
+}
+
+// poll checks the page once and reports whether the login has settled. An
+// error means the page can no longer be read.
+func (t *loginTracker) poll(driver BrowserDriver) (bool, error) {
+	currentURL, err := driver.URL()
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract cookies: %w", err)
+		return false, err
 	}
-	fmt.Printf("Captured %d cookies\n", len(cookies))
 
-	// Extract localStorage from the page we're on
-	pageInfo, _ := page.Info()
-	if pageInfo != nil {
-		fmt.Printf("Reading localStorage from: %s\n", pageInfo.URL)
+	currentParsed, err := url.Parse(currentURL)
+	if err != nil {
+		return false, nil
+	}
+
+	// The opener only settles after its popups have closed
+	if t.popups.update(driver) {
+		t.stableTime = time.Now()
+		return false, nil
+	}
+
+	if currentURL != t.lastURL {
+		t.lastURL = currentURL
+		t.stableTime = time.Now()
+		return false, nil
 	}
 
-	localStorage, err := ExtractLocalStorage(page)
+	isOnHost := currentParsed.Host == t.host
+	isStable := time.Since(t.stableTime) >= t.stableThreshold
+	isPastLanding := !t.skipLanding || (currentParsed.Path != "/landing" && currentParsed.Path != "/landing/")
+
+	return isOnHost && isStable && isPastLanding, nil
+}
+
+// popupWatcher follows the popups a login page opens, e.g. MSAL loginPopup
+// or a "Sign in with Microsoft" window, reporting where they go
+type popupWatcher struct {
+	hosts    map[string]bool // Hosts the open popups are on; nil when none are open
+	progress io.Writer
+}
+
+// update polls the driver's popups and reports whether any are still open
+func (w *popupWatcher) update(driver BrowserDriver) bool {
+	urls, err := driver.Popups()
 	if err != nil {
-		// Non-fatal — some sites don't use localStorage
-		fmt.Printf("Warning: could not read localStorage: %v\n", err)
-		localStorage = map[string]string{}
-	} else {
-		fmt.Printf("Captured %d localStorage entries\n", len(localStorage))
-		if len(localStorage) == 0 {
-			// Debug: list all pages to see if we're on the wrong one
-			pages, _ := browser.Pages()
-			fmt.Printf("Browser has %d pages:\n", len(pages))
-			for i, p := range pages {
-				info, _ := p.Info()
-				if info != nil {
-					fmt.Printf("  [%d] %s\n", i, info.URL)
-				}
-			}
+		return false
+	}
+
+	if len(urls) == 0 {
+		if w.hosts != nil {
+			progressf(w.progress, "Login popup closed\n")
 		}
+		w.hosts = nil
+		return false
 	}
 
-	return &AuthResult{
-		Cookies:      cookies,
-		LocalStorage: localStorage,
-	}, nil
+	hosts := map[string]bool{}
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		hosts[parsed.Host] = true
+		if !w.hosts[parsed.Host] {
+			progressf(w.progress, "Following login popup: %s\n", parsed.Host)
+		}
+	}
+
+	w.hosts = hosts
+	return true
+}
+
+// openDriver returns a driver for the configured browser, over WebDriver or CDP
+func (b *BrowserAuth) openDriver(config *BrowserConfig) (BrowserDriver, error) {
+	if b.newDriver != nil {
+		return b.newDriver(config)
+	}
+	if config.Protocol == ProtocolWebDriver {
+		return openWebDriver(config)
+	}
+
+	browser, needsClose, err := b.getOrLaunchBrowser(config)
+	if err != nil {
+		return nil, err
+	}
+	driver := newCDPDriver(browser, needsClose)
+	driver.silent = config.Silent
+	return driver, nil
 }
 
 // getOrLaunchBrowser connects to an existing debug browser or launches one
 // Returns the browser, whether it needs to be closed, and any error
 func (b *BrowserAuth) getOrLaunchBrowser(config *BrowserConfig) (*rod.Browser, bool, error) {
-	// First, check if browser is already running with debug port
-	if config.IsDebugPortOpen() {
-		fmt.Printf("Connecting to existing %s browser on port %d...\n", config.Type, config.DebugPort)
-		wsURL, err := config.WebSocketDebuggerURL()
-		if err != nil {
-			return nil, false, fmt.Errorf("failed to get WebSocket URL: %w", err)
-		}
-		browser := rod.New().ControlURL(wsURL).MustConnect()
-		// Don't close browser we connected to - user's browser stays open
-		return browser, false, nil
+	// Don't close a browser that outlives us - it is either the user's or one
+	// launched in port mode and recorded so `fetch browser stop` can shut it
+	// down. A pipe-launched browser is ours alone and is closed after use.
+	return ConnectBrowser(config)
+}
+
+// ConnectBrowser connects to the browser described by config. An explicit
+// CDP URL is used as-is; otherwise it reattaches to a browser fetch launched
+// earlier, connects to one already on the debug port, or launches one.
+// owned is true when the browser was launched over a debugging pipe for this
+// connection only; the caller should close it when done.
+func ConnectBrowser(config *BrowserConfig) (browser *rod.Browser, owned bool, err error) {
+	if config.Protocol == ProtocolWebDriver {
+		return nil, false, fmt.Errorf("%s is driven over WebDriver and has no CDP endpoint", config.Type)
 	}
 
-	// Browser not running with debug, launch it
-	fmt.Printf("Launching %s with debug port %d...\n", config.Type, config.DebugPort)
-
-	cmd := exec.Command(config.ExePath,
-		fmt.Sprintf("--remote-debugging-port=%d", config.DebugPort),
-		fmt.Sprintf("--user-data-dir=%s", config.UserDataDir),
-		"--remote-allow-origins=*",
-	)
-	if err := cmd.Start(); err != nil {
-		return nil, false, fmt.Errorf("failed to launch browser: %w", err)
-	}
-
-	// Wait for debug port to become available
-	for i := 0; i < 30; i++ {
-		time.Sleep(500 * time.Millisecond)
-		if config.IsDebugPortOpen() {
-			break
-		}
+	if config.CDPURL != "" {
+		progressf(config.Progress, "Connecting to CDP endpoint %s...\n", config.CDPURL)
+		browser, err = ConnectCDPEndpoint(config.CDPURL, config.ConnectTimeout)
+		return browser, false, err
 	}
 
-	if !config.IsDebugPortOpen() {
-		return nil, false, fmt.Errorf("browser debug port did not open after 15 seconds")
+	// Attach to a browser the bmux CLI launched rather than starting another
+	if browser, err := connectBmuxSession(config); browser != nil || err != nil {
+		return browser, false, err
 	}
 
-	wsURL, err := config.WebSocketDebuggerURL()
+	store, err := NewBrowserStateStore()
 	if err != nil {
-		return nil, false, fmt.Errorf("failed to get WebSocket URL: %w", err)
+		return nil, false, err
 	}
 
-	browser := rod.New().ControlURL(wsURL).MustConnect()
-	// We launched this browser, but don't close it - user may want to keep using it
-	return browser, false, nil
-}
+	// Reuse the port of a browser an earlier run launched, if it is still alive
+	if _, err := reattachManagedBrowser(store, config); err != nil {
+		return nil, false, err
+	}
 
-// rawCookie is a flexible struct for parsing CDP cookie responses
-// Chromium 136+ changed partitionKey from string to object, breaking Rod's types
-type rawCookie struct {
-	Name     string  `json:"name"`
-	Value    string  `json:"value"`
-	Domain   string  `json:"domain"`
-	Path     string  `json:"path"`
-	Expires  float64 `json:"expires"`
-	Secure   bool    `json:"secure"`
-	HTTPOnly bool    `json:"httpOnly"`
-	SameSite string  `json:"sameSite"`
-}
+	mode, err := resolveLaunchMode(config)
+	if err != nil {
+		return nil, false, err
+	}
 
-// extractCookies extracts cookies from the browser and converts them to http.Cookie format
-func (b *BrowserAuth) extractCookies(browser *rod.Browser, host string) ([]*http.Cookie, error) {
-	// Use raw CDP call to avoid Rod's outdated proto types
-	ctx := context.Background()
-	result, err := browser.Call(ctx, "", "Storage.getCookies", nil)
+	// First, check if the browser is already running with its debug port
+	version, err := verifyDebugPort(store, config, mode == LaunchPort)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get cookies from browser: %w", err)
+		return nil, false, err
+	}
+	if version != nil {
+		progressf(config.Progress, "Connecting to existing %s browser (%s) on port %d...\n", config.Type, version.Browser, config.DebugPort)
+		if config.ProfileDirectory != "" {
+			progressf(config.Progress, "Note: the browser is already running, so it keeps its open profile instead of %q\n", config.ProfileDirectory)
+		}
+		browser, err = ConnectCDPEndpoint(config.DebugURL(), config.ConnectTimeout)
+		return browser, false, err
 	}
 
-	// Parse the raw JSON response
-	var response struct {
-		Cookies []rawCookie `json:"cookies"`
+	// A headless browser on the debug port would outlive this login and be
+	// reused by later interactive ones, so silent logins only launch over a pipe
+	if config.Silent && mode != LaunchPipe {
+		return nil, false, fmt.Errorf("no %s debug browser is running, and a headless one can only be launched in pipe mode", config.Type)
 	}
-	if err := json.Unmarshal(result, &response); err != nil {
-		return nil, fmt.Errorf("failed to parse cookies response: %w", err)
+
+	// Browser not running with debug, launch it
+	if mode == LaunchPipe {
+		progressf(config.Progress, "Launching %s with a debugging pipe...\n", config.Type)
+		progressf(config.Progress, "Using %s (%s)\n", config.ExePath, config.ExeReason)
+		browser, err = launchPipeBrowser(config)
+		return browser, err == nil, err
 	}
 
-	var httpCookies []*http.Cookie
-	for _, c := range response.Cookies {
-		sameSite := http.SameSiteDefaultMode
-		switch c.SameSite {
-		case "Strict":
-			sameSite = http.SameSiteStrictMode
-		case "Lax":
-			sameSite = http.SameSiteLaxMode
-		case "None":
-			sameSite = http.SameSiteNoneMode
-		}
+	progressf(config.Progress, "Launching %s with debug port %d...\n", config.Type, config.DebugPort)
+	progressf(config.Progress, "Using %s (%s)\n", config.ExePath, config.ExeReason)
 
-		cookie := &http.Cookie{
-			Name:     c.Name,
-			Value:    c.Value,
-			Path:     c.Path,
-			Domain:   c.Domain,
-			Expires:  time.Unix(int64(c.Expires), 0),
-			Secure:   c.Secure,
-			HttpOnly: c.HTTPOnly,
-			SameSite: sameSite,
+	if _, err := launchBrowserProcess(store, config); err != nil {
+		return nil, false, err
+	}
+
+	browser, err = ConnectCDPEndpoint(config.DebugURL(), config.ConnectTimeout)
+	return browser, false, err
+}
+
+// connectBmuxSession connects to the bmux session for config, if there is
+// one. A session named with --bmux-session must be reachable and serve the
+// configured browser; a discovered one is skipped when it does not.
+func connectBmuxSession(config *BrowserConfig) (*rod.Browser, error) {
+	session, err := resolveBmuxSession(config)
+	if err != nil || session == nil {
+		return nil, err
+	}
+
+	sessionConfig := *config
+	sessionConfig.DebugPort = session.Port
+	version, err := sessionConfig.DebugVersion()
+	if err == nil {
+		err = version.Matches(config.Type)
+	}
+	if err != nil {
+		if config.BmuxSession == "" {
+			return nil, nil
 		}
-		httpCookies = append(httpCookies, cookie)
+		return nil, fmt.Errorf("bmux session %q on port %d: %w", session.Name, session.Port, err)
 	}
 
-	return httpCookies, nil
+	progressf(config.Progress, "Connecting to bmux session %q (%s) on port %d...\n", session.Name, version.Browser, session.Port)
+	if config.ProfileDirectory != "" {
+		progressf(config.Progress, "Note: the browser is already running, so it keeps its open profile instead of %q\n", config.ProfileDirectory)
+	}
+	config.DebugPort = session.Port
+	return ConnectCDPEndpoint(config.DebugURL(), config.ConnectTimeout)
 }
 
 // convertCookies converts proto.NetworkCookie to http.Cookie
@@ -3,52 +3,184 @@ package auth
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
 	"time"
 )
 
-// BrowserType represents a supported browser
+// BrowserType names a browser in the registry (the --browser value)
 type BrowserType string
 
+// Built-in browsers; more can be added through the config file
 const (
-	BrowserEdge   BrowserType = "edge"
-	BrowserChrome BrowserType = "chrome"
+	BrowserEdge     BrowserType = "edge"
+	BrowserChrome   BrowserType = "chrome"
+	BrowserBrave    BrowserType = "brave"
+	BrowserChromium BrowserType = "chromium"
+	BrowserVivaldi  BrowserType = "vivaldi"
+	BrowserFirefox  BrowserType = "firefox"
+	BrowserSafari   BrowserType = "safari"
+)
+
+// Protocol is how fetch drives a browser
+type Protocol string
+
+const (
+	// ProtocolCDP drives Chromium browsers over the Chrome DevTools Protocol
+	ProtocolCDP Protocol = "cdp"
+	// ProtocolWebDriver drives a browser through a W3C WebDriver server
+	// such as safaridriver or geckodriver; ExePath is then the driver
+	ProtocolWebDriver Protocol = "webdriver"
 )
 
 // BrowserConfig holds configuration for a browser
 type BrowserConfig struct {
 	Type        BrowserType
+	DisplayName string
+	Protocol    Protocol
 	ExePath     string
+	ExeReason   string // Why ExePath was chosen, for diagnostics
+	ExeFound    bool   // ExePath was configured or found by discovery
 	UserDataDir string
 	DebugPort   int
+
+	ProfileDirectory string // Profile inside UserDataDir (--profile-directory); browser default when empty
+
+	SourceUserDataDir string // The browser's everyday user-data dir, which clone-profile copies from
+
+	CDPURL         string        // Connect here instead of launching; see ResolveCDPEndpoint
+	ConnectTimeout time.Duration // Bound on connecting to the browser
+
+	DebugHost string // Host serving the debug port; 127.0.0.1 when empty
+	WSLBridge bool   // Connect through the WSL portproxy (DebugPort+10000) on DebugHost
+
+	WebDriverURL string // WebDriver server to use instead of starting the driver on DebugPort
+
+	BmuxSession string // Attach to this bmux session; see resolveBmuxSession
+
+	LaunchMode LaunchMode // How to launch the browser when none is running; see resolveLaunchMode
+
+	ReuseTab bool // Log in through an open tab on the target's origin when there is one
+
+	StorageOrigin string // Capture localStorage of this origin instead of the page's; see NormalizeOrigin
+
+	Silent bool // Log in out of sight: launch headless, or use a background tab; see SilentAuthenticate
+
+	Progress io.Writer // Receives progress messages; os.Stdout when nil
 }
 
 // GetBrowserConfig returns the configuration for the specified browser type
 func GetBrowserConfig(browserType BrowserType) (*BrowserConfig, error) {
-	switch browserType {
-	case BrowserEdge:
-		return &BrowserConfig{
-			Type:        BrowserEdge,
-			ExePath:     findEdgePath(),
-			UserDataDir: os.ExpandEnv("$LOCALAPPDATA/Microsoft/EdgeDebug"),
-			DebugPort:   9222,
-		}, nil
-	case BrowserChrome:
-		return &BrowserConfig{
-			Type:        BrowserChrome,
-			ExePath:     findChromePath(),
-			UserDataDir: os.ExpandEnv("$LOCALAPPDATA/Google/ChromeDebug"),
-			DebugPort:   9223,
-		}, nil
-	default:
-		return nil, fmt.Errorf("unsupported browser type: %s", browserType)
+	userConfig, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	registry := NewBrowserRegistry()
+	if err := registry.Apply(userConfig); err != nil {
+		return nil, err
+	}
+
+	def, err := registry.Lookup(browserType)
+	if err != nil {
+		return nil, err
+	}
+
+	config := hostDiscoveryEnv().browserConfig(def)
+	if config.Protocol == ProtocolCDP {
+		applyWSLBridge(config)
 	}
+	config.CDPURL = userConfig.CDPURL
+	config.ReuseTab = userConfig.ReuseTab
+	config.LaunchMode, err = ParseLaunchMode(userConfig.LaunchMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid launch_mode in config: %w", err)
+	}
+	config.ConnectTimeout = DefaultConnectTimeout
+	if userConfig.ConnectTimeout != "" {
+		timeout, err := time.ParseDuration(userConfig.ConnectTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid connect_timeout in config: %w", err)
+		}
+		config.ConnectTimeout = timeout
+	}
+
+	return config, nil
+}
+
+// browserConfig resolves a registry entry into a concrete configuration
+// using the given discovery environment
+func (e discoveryEnv) browserConfig(def *BrowserDefinition) *BrowserConfig {
+	config := &BrowserConfig{
+		Type:        def.Name,
+		DisplayName: def.DisplayName,
+		Protocol:    def.Protocol,
+		ExePath:     def.ExePath,
+		ExeReason:   "set in config",
+		ExeFound:    true,
+		UserDataDir: def.UserDataDir,
+		DebugPort:   def.DebugPort,
+	}
+
+	if config.ExePath == "" {
+		exe := e.discover(def.candidates, def.fallbackExe)
+		config.ExePath = exe.Path
+		config.ExeReason = exe.Reason
+		config.ExeFound = exe.Found
+	}
+
+	if config.Protocol == "" {
+		config.Protocol = ProtocolCDP
+	}
+
+	// Browsers without a debug profile (Safari) leave UserDataDir empty
+	if config.UserDataDir == "" && def.debugDirs != (platformDirs{}) {
+		config.UserDataDir = e.userDataDir(def.debugDirs)
+	}
+	if def.userDirs != (platformDirs{}) {
+		config.SourceUserDataDir = e.userDataDir(def.userDirs)
+	}
+
+	return config
+}
+
+// launchArgs returns the command line for launching the browser, after the
+// arguments that expose CDP
+func (c *BrowserConfig) launchArgs(debugArgs ...string) []string {
+	args := append(debugArgs, fmt.Sprintf("--user-data-dir=%s", c.UserDataDir))
+	if c.ProfileDirectory != "" {
+		args = append(args, fmt.Sprintf("--profile-directory=%s", c.ProfileDirectory))
+	}
+	if c.Silent {
+		args = append(args, "--headless=new")
+	}
+	return args
+}
+
+// portLaunchArgs returns the command line for running the browser with its
+// debug port open, as fetch browser start and fetch setup launchers do
+func (c *BrowserConfig) portLaunchArgs() []string {
+	return c.launchArgs(
+		fmt.Sprintf("--remote-debugging-port=%d", c.DebugPort),
+	)
 }
 
 // DebugURL returns the debug endpoint URL for this browser
 func (c *BrowserConfig) DebugURL() string {
-	return fmt.Sprintf("http://127.0.0.1:%d", c.DebugPort)
+	host := c.DebugHost
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	return fmt.Sprintf("http://%s:%d", host, c.ConnectPort())
+}
+
+// ConnectPort returns the port to connect to, which is the portproxy port
+// rather than the browser's own debug port when bridging from WSL
+func (c *BrowserConfig) ConnectPort() int {
+	if c.WSLBridge {
+		return WSLProxyPort(c.DebugPort)
+	}
+	return c.DebugPort
 }
 
 // WebSocketDebuggerURL returns the WebSocket URL for CDP connection
@@ -76,43 +208,16 @@ func (c *BrowserConfig) WebSocketDebuggerURL() (string, error) {
 
 // IsDebugPortOpen checks if the browser's debug port is responding
 func (c *BrowserConfig) IsDebugPortOpen() bool {
+	return debugEndpointResponds(c.DebugURL())
+}
+
+// debugEndpointResponds checks if a debug endpoint answers /json/version
+func debugEndpointResponds(debugURL string) bool {
 	client := &http.Client{Timeout: 500 * time.Millisecond}
-	resp, err := client.Get(c.DebugURL() + "/json/version")
+	resp, err := client.Get(debugURL + "/json/version")
 	if err != nil {
 		return false
 	}
 	resp.Body.Close()
 	return resp.StatusCode == 200
 }
-
-// findEdgePath returns the path to Edge executable
-func findEdgePath() string {
-	paths := []string{
-		os.ExpandEnv("$PROGRAMFILES(X86)/Microsoft/Edge/Application/msedge.exe"),
-		os.ExpandEnv("$PROGRAMFILES/Microsoft/Edge/Application/msedge.exe"),
-		"C:/Program Files (x86)/Microsoft/Edge/Application/msedge.exe",
-		"C:/Program Files/Microsoft/Edge/Application/msedge.exe",
-	}
-	for _, p := range paths {
-		if _, err := os.Stat(p); err == nil {
-			return p
-		}
-	}
-	return "msedge.exe" // Fallback to PATH
-}
-
-// findChromePath returns the path to Chrome executable
-func findChromePath() string {
-	paths := []string{
-		os.ExpandEnv("$PROGRAMFILES/Google/Chrome/Application/chrome.exe"),
-		os.ExpandEnv("$PROGRAMFILES(X86)/Google/Chrome/Application/chrome.exe"),
-		"C:/Program Files/Google/Chrome/Application/chrome.exe",
-		"C:/Program Files (x86)/Google/Chrome/Application/chrome.exe",
-	}
-	for _, p := range paths {
-		if _, err := os.Stat(p); err == nil {
-			return p
-		}
-	}
-	return "chrome.exe" // Fallback to PATH
-}
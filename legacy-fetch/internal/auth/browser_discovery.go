@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"runtime"
+)
+
+// BrowserDiscovery records which executable was chosen for a browser and why
+type BrowserDiscovery struct {
+	Path   string
+	Reason string
+	Found  bool // Path exists; false when it is only a fallback name
+}
+
+// browserCandidates lists where a browser may be installed on one platform
+type browserCandidates struct {
+	Commands []string // Names looked up on PATH, in order of preference
+	Paths    []string // Install locations checked when PATH has none; ~ is the home directory
+}
+
+// discoveryEnv abstracts the OS lookups used for discovery so tests can fake them
+type discoveryEnv struct {
+	goos     string // runtime.GOOS, or "wsl" to discover Windows browsers from WSL
+	homeDir  string
+	getenv   func(string) string
+	lookPath func(string) (string, error)
+	exists   func(string) bool
+}
+
+// hostDiscoveryEnv returns a discoveryEnv backed by the real OS
+func hostDiscoveryEnv() discoveryEnv {
+	homeDir, _ := os.UserHomeDir()
+	goos, getenv := runtime.GOOS, os.Getenv
+	if IsWSL() {
+		// Drive the Windows browser; its paths and env come from the Windows side
+		goos, getenv = "wsl", windowsGetenv
+	}
+	return discoveryEnv{
+		goos:     goos,
+		homeDir:  homeDir,
+		getenv:   getenv,
+		lookPath: exec.LookPath,
+		exists: func(name string) bool {
+			info, err := os.Stat(name)
+			return err == nil && !info.IsDir()
+		},
+	}
+}
+
+// expand resolves $VARS and a leading ~ in a candidate path
+func (e discoveryEnv) expand(candidate string) string {
+	if len(candidate) > 0 && candidate[0] == '~' {
+		candidate = e.homeDir + candidate[1:]
+	}
+	return os.Expand(candidate, e.getenv)
+}
+
+// discover picks the executable for a browser from its candidates.
+// PATH is consulted first so the user's own environment wins, then the
+// well-known install locations. If nothing is found the first command name
+// is returned so the launch error names a sensible binary.
+func (e discoveryEnv) discover(candidates map[string]browserCandidates, fallback string) BrowserDiscovery {
+	platformKey := e.goos
+	if e.goos == "wsl" {
+		platformKey = "windows"
+	}
+
+	platform, ok := candidates[platformKey]
+	if !ok {
+		return BrowserDiscovery{Path: fallback, Reason: fmt.Sprintf("no known install locations for %s", e.goos)}
+	}
+
+	for _, name := range platform.Commands {
+		if exePath, err := e.lookPath(name); err == nil {
+			return BrowserDiscovery{Path: exePath, Reason: fmt.Sprintf("found %s on PATH", name), Found: true}
+		}
+	}
+
+	for _, candidate := range platform.Paths {
+		exePath := e.expand(candidate)
+		if e.goos == "wsl" {
+			exePath = wslMountPath(exePath)
+		}
+		if e.exists(exePath) {
+			return BrowserDiscovery{Path: exePath, Reason: "found at known install location", Found: true}
+		}
+	}
+
+	if len(platform.Commands) > 0 {
+		fallback = platform.Commands[0]
+	}
+	return BrowserDiscovery{Path: fallback, Reason: "not found; falling back to PATH lookup at launch"}
+}
+
+// userDataDir resolves a user-data dir under the platform's per-user
+// application data location. The debug profile is kept apart from the
+// browser's normal one because Chromium 136+ refuses --remote-debugging-port
+// on the default user-data dir.
+func (e discoveryEnv) userDataDir(dirs platformDirs) string {
+	switch e.goos {
+	case "windows":
+		return path.Join(e.getenv("LOCALAPPDATA"), dirs.Windows)
+	case "wsl":
+		// Handed to the Windows browser, so it must be a Windows path
+		return windowsStylePath(path.Join(e.getenv("LOCALAPPDATA"), dirs.Windows))
+	case "darwin":
+		return path.Join(e.homeDir, "Library", "Application Support", dirs.Darwin)
+	default:
+		configHome := e.getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = path.Join(e.homeDir, ".config")
+		}
+		return path.Join(configHome, dirs.Linux)
+	}
+}
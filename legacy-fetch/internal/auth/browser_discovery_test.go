@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeDiscoveryEnv builds a discoveryEnv with the given commands on PATH
+// and files on disk
+func fakeDiscoveryEnv(goos string, onPath map[string]string, files ...string) discoveryEnv {
+	existing := map[string]bool{}
+	for _, f := range files {
+		existing[f] = true
+	}
+	return discoveryEnv{
+		goos:    goos,
+		homeDir: "/home/dev",
+		getenv: func(key string) string {
+			switch key {
+			case "LOCALAPPDATA":
+				return "C:/Users/dev/AppData/Local"
+			case "APPDATA":
+				return "C:/Users/dev/AppData/Roaming"
+			}
+			return ""
+		},
+		lookPath: func(name string) (string, error) {
+			if path, ok := onPath[name]; ok {
+				return path, nil
+			}
+			return "", errors.New("not found")
+		},
+		exists: func(path string) bool { return existing[path] },
+	}
+}
+
+// builtinDefinition returns a built-in registry entry by name
+func builtinDefinition(t *testing.T, name BrowserType) *BrowserDefinition {
+	t.Helper()
+	def, err := NewBrowserRegistry().Lookup(name)
+	if err != nil {
+		t.Fatalf("Lookup(%s) failed: %v", name, err)
+	}
+	return def
+}
+
+func TestDiscover_LinuxPrefersPATH(t *testing.T) {
+	env := fakeDiscoveryEnv("linux",
+		map[string]string{"google-chrome": "/usr/bin/google-chrome"},
+		"/opt/google/chrome/chrome",
+	)
+
+	got := env.discover(builtinDefinition(t, BrowserChrome).candidates, "chrome")
+	if got.Path != "/usr/bin/google-chrome" {
+		t.Errorf("Path = %q, want /usr/bin/google-chrome", got.Path)
+	}
+	if !strings.Contains(got.Reason, "google-chrome on PATH") {
+		t.Errorf("Reason = %q, want mention of PATH lookup", got.Reason)
+	}
+	if !got.Found {
+		t.Error("expected the PATH lookup to count as found")
+	}
+}
+
+func TestDiscover_LinuxFlatpak(t *testing.T) {
+	env := fakeDiscoveryEnv("linux", nil,
+		"/home/dev/.local/share/flatpak/exports/bin/com.microsoft.Edge",
+	)
+
+	got := env.discover(builtinDefinition(t, BrowserEdge).candidates, "msedge")
+	if got.Path != "/home/dev/.local/share/flatpak/exports/bin/com.microsoft.Edge" {
+		t.Errorf("Path = %q, want user flatpak export", got.Path)
+	}
+	if got.Reason != "found at known install location" || !got.Found {
+		t.Errorf("Reason = %q, Found = %v", got.Reason, got.Found)
+	}
+}
+
+func TestDiscover_MacAppBundle(t *testing.T) {
+	env := fakeDiscoveryEnv("darwin", nil,
+		"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+	)
+
+	got := env.discover(builtinDefinition(t, BrowserEdge).candidates, "msedge")
+	if got.Path != "/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge" {
+		t.Errorf("Path = %q, want Edge .app bundle", got.Path)
+	}
+}
+
+func TestDiscover_WindowsExpandsEnv(t *testing.T) {
+	env := fakeDiscoveryEnv("windows", nil,
+		"C:/Program Files/Google/Chrome/Application/chrome.exe",
+	)
+
+	got := env.discover(builtinDefinition(t, BrowserChrome).candidates, "chrome")
+	if got.Path != "C:/Program Files/Google/Chrome/Application/chrome.exe" {
+		t.Errorf("Path = %q, want Program Files chrome.exe", got.Path)
+	}
+}
+
+func TestDiscover_NotFoundFallsBack(t *testing.T) {
+	env := fakeDiscoveryEnv("linux", nil)
+
+	got := env.discover(builtinDefinition(t, BrowserEdge).candidates, "msedge")
+	if got.Path != "microsoft-edge-stable" {
+		t.Errorf("Path = %q, want first PATH command as fallback", got.Path)
+	}
+	if !strings.Contains(got.Reason, "not found") {
+		t.Errorf("Reason = %q, want not-found explanation", got.Reason)
+	}
+	if got.Found {
+		t.Error("expected the fallback name not to count as found")
+	}
+}
+
+func TestBrowserConfig_DefaultProfileDirs(t *testing.T) {
+	tests := []struct {
+		goos string
+		want string
+	}{
+		{goos: "windows", want: "C:/Users/dev/AppData/Local/Microsoft/EdgeDebug"},
+		{goos: "darwin", want: "/home/dev/Library/Application Support/Microsoft/EdgeDebug"},
+		{goos: "linux", want: "/home/dev/.config/microsoft-edge-debug"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			config := fakeDiscoveryEnv(tt.goos, nil).browserConfig(builtinDefinition(t, BrowserEdge))
+			if config.UserDataDir != tt.want {
+				t.Errorf("UserDataDir = %q, want %q", config.UserDataDir, tt.want)
+			}
+		})
+	}
+}
+
+func TestBrowserConfig_WebDriverBrowsers(t *testing.T) {
+	env := fakeDiscoveryEnv("darwin", nil, "/usr/bin/safaridriver")
+
+	safari := env.browserConfig(builtinDefinition(t, BrowserSafari))
+	if safari.Protocol != ProtocolWebDriver {
+		t.Errorf("Protocol = %q, want webdriver", safari.Protocol)
+	}
+	if safari.ExePath != "/usr/bin/safaridriver" {
+		t.Errorf("ExePath = %q, want safaridriver", safari.ExePath)
+	}
+	if safari.UserDataDir != "" {
+		t.Errorf("UserDataDir = %q, want none for Safari", safari.UserDataDir)
+	}
+
+	edge := env.browserConfig(builtinDefinition(t, BrowserEdge))
+	if edge.Protocol != ProtocolCDP {
+		t.Errorf("Protocol = %q, want cdp by default", edge.Protocol)
+	}
+}
+
+func TestBrowserConfig_SourceUserDataDir(t *testing.T) {
+	config := fakeDiscoveryEnv("windows", nil).browserConfig(builtinDefinition(t, BrowserEdge))
+	if config.SourceUserDataDir != "C:/Users/dev/AppData/Local/Microsoft/Edge/User Data" {
+		t.Errorf("SourceUserDataDir = %q, want Edge's own User Data", config.SourceUserDataDir)
+	}
+
+	config = fakeDiscoveryEnv("linux", nil).browserConfig(builtinDefinition(t, BrowserChrome))
+	if config.SourceUserDataDir != "/home/dev/.config/google-chrome" {
+		t.Errorf("SourceUserDataDir = %q, want ~/.config/google-chrome", config.SourceUserDataDir)
+	}
+}
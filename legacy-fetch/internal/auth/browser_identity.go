@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DebugVersion is the /json/version document a DevTools endpoint serves
+type DebugVersion struct {
+	Browser              string `json:"Browser"`
+	ProtocolVersion      string `json:"Protocol-Version"`
+	UserAgent            string `json:"User-Agent"`
+	V8Version            string `json:"V8-Version"`
+	WebKitVersion        string `json:"WebKit-Version"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// errNoDebugListener means nothing accepted a connection on the debug port
+var errNoDebugListener = fmt.Errorf("nothing is listening")
+
+// fetchDebugVersion reads and validates /json/version from a debug endpoint.
+// Returns errNoDebugListener when the port is closed, and a descriptive
+// error when whatever answers is not a DevTools endpoint.
+func fetchDebugVersion(debugURL string, timeout time.Duration) (*DebugVersion, error) {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(debugURL + "/json/version")
+	if err != nil {
+		return nil, errNoDebugListener
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s is not a DevTools endpoint (/json/version returned %s)", debugURL, resp.Status)
+	}
+
+	var version DebugVersion
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil || version.Browser == "" || version.WebSocketDebuggerURL == "" {
+		return nil, fmt.Errorf("%s is not a DevTools endpoint (unexpected /json/version response)", debugURL)
+	}
+	return &version, nil
+}
+
+// DebugVersion returns what the browser on the debug port reports about itself
+func (c *BrowserConfig) DebugVersion() (*DebugVersion, error) {
+	return fetchDebugVersion(c.DebugURL(), 500*time.Millisecond)
+}
+
+// vendorTokens are the User-Agent tokens that set a Chromium browser apart
+// from Chrome. Brave, Vivaldi and Chromium send Chrome's User-Agent
+// unchanged, so they can be told apart from Edge and Opera but not from
+// Chrome or each other.
+var vendorTokens = map[string]string{
+	"Edg/": "Microsoft Edge",
+	"OPR/": "Opera",
+}
+
+// expectedVendorToken lists the built-in CDP browsers and the vendor token
+// each must send; "" means none of the vendorTokens may appear
+var expectedVendorToken = map[BrowserType]string{
+	BrowserEdge:     "Edg/",
+	BrowserChrome:   "",
+	BrowserBrave:    "",
+	BrowserChromium: "",
+	BrowserVivaldi:  "",
+}
+
+// Product names the browser for messages, e.g. "Microsoft Edge (Edg/130.0.2849.80)"
+func (v *DebugVersion) Product() string {
+	for token, name := range vendorTokens {
+		if strings.Contains(v.UserAgent, token) {
+			return fmt.Sprintf("%s (%s)", name, v.Browser)
+		}
+	}
+	return v.Browser
+}
+
+// Matches checks that the endpoint is the requested kind of browser and speaks
+// a supported protocol version. Browsers added through the config file are
+// only required to be a DevTools endpoint.
+func (v *DebugVersion) Matches(browserType BrowserType) error {
+	if v.ProtocolVersion != "" && !strings.HasPrefix(v.ProtocolVersion, "1.") {
+		return fmt.Errorf("%s speaks DevTools protocol %s, which fetch does not support", v.Browser, v.ProtocolVersion)
+	}
+
+	want, builtin := expectedVendorToken[browserType]
+	if !builtin {
+		return nil
+	}
+
+	mismatch := fmt.Errorf("debug port is served by %s, not %s", v.Product(), browserType)
+	if !strings.Contains(v.UserAgent, "Chrome/") {
+		return mismatch
+	}
+	for token := range vendorTokens {
+		if strings.Contains(v.UserAgent, token) != (token == want) {
+			return mismatch
+		}
+	}
+	return nil
+}
+
+// freeDebugPort asks the OS for an unused localhost port
+func freeDebugPort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free debug port: %w", err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// verifyDebugPort checks what answers on the config's debug port. It returns
+// the version when the requested browser is already there, and nil when the
+// port is free. If something else holds the port, a stale record for it is
+// forgotten and, when needPort is set, config moves to a free port.
+func verifyDebugPort(store *BrowserStateStore, config *BrowserConfig, needPort bool) (*DebugVersion, error) {
+	version, err := config.DebugVersion()
+	if err == errNoDebugListener {
+		return nil, nil
+	}
+	if err == nil {
+		err = version.Matches(config.Type)
+	}
+	if err == nil {
+		return version, nil
+	}
+
+	// Whatever fetch recorded on this port is gone
+	if record, _ := store.Get(config.Type); record != nil && record.Port == config.DebugPort {
+		_ = store.Remove(config.Type)
+	}
+
+	if !needPort {
+		progressf(config.Progress, "Ignoring port %d: %v\n", config.DebugPort, err)
+		return nil, nil
+	}
+
+	if config.WSLBridge {
+		// The portproxy is set up for a fixed port, so moving is no help
+		return nil, fmt.Errorf("port %d: %w; stop it or set another debug_port for %s in ~/.omatic/fetch.json",
+			config.DebugPort, err, config.Type)
+	}
+
+	port, portErr := freeDebugPort()
+	if portErr != nil {
+		return nil, portErr
+	}
+	progressf(config.Progress, "Port %d: %v; using port %d instead\n", config.DebugPort, err, port)
+	config.DebugPort = port
+	return nil, nil
+}
@@ -0,0 +1,114 @@
+package auth
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	chromeUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36"
+	edgeUA   = chromeUA + " Edg/130.0.2849.80"
+	operaUA  = chromeUA + " OPR/114.0.0.0"
+)
+
+func TestDebugVersion_Matches(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     DebugVersion
+		browserType BrowserType
+		wantErr     bool
+	}{
+		{"edge is edge", DebugVersion{Browser: "Edg/130.0.2849.80", UserAgent: edgeUA, ProtocolVersion: "1.3"}, BrowserEdge, false},
+		{"chrome is chrome", DebugVersion{Browser: "Chrome/130.0.0.0", UserAgent: chromeUA, ProtocolVersion: "1.3"}, BrowserChrome, false},
+		{"brave looks like chrome", DebugVersion{Browser: "Chrome/130.0.0.0", UserAgent: chromeUA}, BrowserBrave, false},
+		{"chrome is not edge", DebugVersion{Browser: "Chrome/130.0.0.0", UserAgent: chromeUA}, BrowserEdge, true},
+		{"edge is not chrome", DebugVersion{Browser: "Edg/130.0.2849.80", UserAgent: edgeUA}, BrowserChrome, true},
+		{"opera is not chromium", DebugVersion{Browser: "Chrome/130.0.0.0", UserAgent: operaUA}, BrowserChromium, true},
+		{"firefox is not chrome", DebugVersion{Browser: "Firefox/131.0", UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0"}, BrowserChrome, true},
+		{"config browsers only need DevTools", DebugVersion{Browser: "Chrome/130.0.0.0", UserAgent: operaUA}, "opera", false},
+		{"unsupported protocol", DebugVersion{Browser: "Chrome/130.0.0.0", UserAgent: chromeUA, ProtocolVersion: "2.0"}, BrowserChrome, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.version.Matches(tt.browserType)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Matches(%s) error = %v, wantErr %v", tt.browserType, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchDebugVersion(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json/version", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"Browser":              "Edg/130.0.2849.80",
+			"Protocol-Version":     "1.3",
+			"User-Agent":           edgeUA,
+			"webSocketDebuggerUrl": "ws://127.0.0.1:9222/devtools/browser/abc",
+		})
+	})
+	devtools := httptest.NewServer(mux)
+	defer devtools.Close()
+
+	version, err := fetchDebugVersion(devtools.URL, time.Second)
+	if err != nil {
+		t.Fatalf("fetchDebugVersion failed: %v", err)
+	}
+	if version.Product() != "Microsoft Edge (Edg/130.0.2849.80)" {
+		t.Errorf("Product() = %q", version.Product())
+	}
+
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer other.Close()
+
+	_, err = fetchDebugVersion(other.URL, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "not a DevTools endpoint") {
+		t.Errorf("expected non-DevTools error, got %v", err)
+	}
+
+	closedURL := other.URL
+	other.Close()
+	if _, err := fetchDebugVersion(closedURL, time.Second); err != errNoDebugListener {
+		t.Errorf("expected errNoDebugListener for a closed port, got %v", err)
+	}
+}
+
+func TestVerifyDebugPort_MovesOffForeignPort(t *testing.T) {
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer foreign.Close()
+
+	_, portStr, _ := net.SplitHostPort(strings.TrimPrefix(foreign.URL, "http://"))
+	port, _ := strconv.Atoi(portStr)
+
+	store := &BrowserStateStore{path: filepath.Join(t.TempDir(), "browsers.json")}
+	store.Save(&LaunchedBrowser{Type: BrowserEdge, PID: os.Getpid(), Port: port})
+
+	config := &BrowserConfig{Type: BrowserEdge, DebugPort: port}
+	version, err := verifyDebugPort(store, config, true)
+	if err != nil {
+		t.Fatalf("verifyDebugPort failed: %v", err)
+	}
+	if version != nil {
+		t.Error("expected no version for a foreign port")
+	}
+	if config.DebugPort == port || config.DebugPort == 0 {
+		t.Errorf("DebugPort = %d, want a different free port", config.DebugPort)
+	}
+	if record, _ := store.Get(BrowserEdge); record != nil {
+		t.Error("expected the stale record for the foreign port to be removed")
+	}
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// StartBrowser launches a debug browser for the config and records it in the
+// browser state file. It always uses port mode, since a browser that outlives
+// fetch must be reachable by later runs. If the requested browser already
+// answers on the debug port, nothing is launched and alreadyRunning is true;
+// the returned record is nil when that browser was not started by fetch. If
+// something else holds the port, the browser is launched on a free port.
+func StartBrowser(config *BrowserConfig) (launched *LaunchedBrowser, alreadyRunning bool, err error) {
+	if config.Protocol == ProtocolWebDriver {
+		return nil, false, fmt.Errorf("%s is driven over WebDriver; its driver is started on demand by fetch auth", config.Type)
+	}
+
+	store, err := NewBrowserStateStore()
+	if err != nil {
+		return nil, false, err
+	}
+
+	record, err := reattachManagedBrowser(store, config)
+	if err != nil {
+		return nil, false, err
+	}
+
+	version, err := verifyDebugPort(store, config, true)
+	if err != nil {
+		return nil, false, err
+	}
+	if version != nil {
+		return record, true, nil
+	}
+
+	launched, err = launchBrowserProcess(store, config)
+	if err != nil {
+		return nil, false, err
+	}
+	return launched, false, nil
+}
+
+// StopBrowser shuts down the browser fetch launched for the config's type.
+// It first asks the browser to close over CDP so profile state is flushed,
+// then falls back to terminating the process. wasRunning is false when the
+// recorded process had already exited and only its record was removed.
+func StopBrowser(config *BrowserConfig) (record *LaunchedBrowser, wasRunning bool, err error) {
+	store, err := NewBrowserStateStore()
+	if err != nil {
+		return nil, false, err
+	}
+
+	record, err = store.Get(config.Type)
+	if err != nil {
+		return nil, false, err
+	}
+	if record == nil {
+		return nil, false, fmt.Errorf("no %s browser was launched by fetch", config.Type)
+	}
+
+	if !record.Alive() {
+		// Already gone - just forget it
+		return record, false, store.Remove(config.Type)
+	}
+
+	recordConfig := *config
+	recordConfig.DebugPort = record.Port
+	// Only close over CDP if the port still serves our kind of browser
+	if version, err := recordConfig.DebugVersion(); err == nil && version.Matches(config.Type) == nil {
+		if browser, err := ConnectCDPEndpoint(recordConfig.DebugURL(), config.ConnectTimeout); err == nil {
+			_ = browser.Close()
+		}
+	}
+
+	if !waitForExit(record, 10*time.Second) {
+		// The PID may belong to another process by now; only signal it
+		// when it is the one fetch started
+		if !record.SameProcess() {
+			return nil, true, fmt.Errorf("browser did not close over CDP, and process %d cannot be confirmed to be the browser fetch launched; stop it yourself", record.PID)
+		}
+		if err := terminateProcess(record.PID); err != nil {
+			return nil, true, fmt.Errorf("failed to terminate browser process %d: %w", record.PID, err)
+		}
+		if !waitForExit(record, 5*time.Second) {
+			return nil, true, fmt.Errorf("browser process %d did not exit", record.PID)
+		}
+	}
+
+	return record, true, store.Remove(config.Type)
+}
+
+// reattachManagedBrowser points the config at the port of a browser fetch
+// launched earlier, and forgets records whose process has died.
+// Returns the live record, or nil if there is none.
+func reattachManagedBrowser(store *BrowserStateStore, config *BrowserConfig) (*LaunchedBrowser, error) {
+	record, err := store.Get(config.Type)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, nil
+	}
+
+	if !record.Alive() {
+		return nil, store.Remove(config.Type)
+	}
+
+	config.DebugPort = record.Port
+	return record, nil
+}
+
+// launchBrowserProcess starts the browser with its debug port open, waits for
+// the port to respond, and records the process in the state store
+func launchBrowserProcess(store *BrowserStateStore, config *BrowserConfig) (*LaunchedBrowser, error) {
+	cmd := exec.Command(config.ExePath, config.portLaunchArgs()...)
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+
+	launched := &LaunchedBrowser{
+		Type:        config.Type,
+		PID:         cmd.Process.Pid,
+		Port:        config.DebugPort,
+		ExePath:     config.ExePath,
+		UserDataDir: config.UserDataDir,
+		StartedAt:   time.Now(),
+		Interop:     isInteropExe(config.ExePath),
+		DebugURL:    config.DebugURL(),
+	}
+	if start, err := processStartTime(launched.PID); err == nil {
+		launched.ProcessStart = start
+	}
+	if err := store.Save(launched); err != nil {
+		return nil, err
+	}
+
+	// Wait for debug port to become available
+	for i := 0; i < 30; i++ {
+		time.Sleep(500 * time.Millisecond)
+		if config.IsDebugPortOpen() {
+			return launched, nil
+		}
+	}
+
+	_ = terminateProcess(launched.PID)
+	_ = store.Remove(config.Type)
+
+	if config.WSLBridge {
+		return nil, fmt.Errorf("browser debug port %s did not open after 15 seconds:\n  %s",
+			config.DebugURL(), strings.Join(WSLBridgeDiagnostics(config), "\n  "))
+	}
+	return nil, fmt.Errorf("browser debug port did not open after 15 seconds")
+}
+
+// waitForExit polls until the browser is gone or the timeout passes
+// Returns true if the browser exited
+func waitForExit(launched *LaunchedBrowser, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !launched.Alive() {
+			return true
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return !launched.Alive()
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// AuthOutcome is the result of one site's login in AuthenticateAll
+type AuthOutcome struct {
+	URL      string
+	Host     string
+	Cookies  int           // Cookies saved for Host
+	Duration time.Duration // From opening the site to saving its session
+	Err      error
+}
+
+// tabDriver is a driver that can open more tabs over its browser
+// connection, so logins can run side by side
+type tabDriver interface {
+	BrowserDriver
+	// Tab returns a driver for a new tab; closing it leaves the others open
+	Tab() BrowserDriver
+}
+
+// AuthenticateAll logs in to several sites over one browser connection. Each
+// site opens in its own tab, its login is tracked on its own, and its session
+// is saved as soon as that login completes. Pressing Enter completes every
+// login still in progress. WebDriver browsers, which drive a single window,
+// log in to the sites one after another instead.
+func (b *BrowserAuth) AuthenticateAll(targetURLs []string) ([]*AuthOutcome, error) {
+	outcomes := make([]*AuthOutcome, len(targetURLs))
+	hosts := map[string]bool{}
+	for i, targetURL := range targetURLs {
+		outcome := &AuthOutcome{URL: targetURL}
+		outcomes[i] = outcome
+
+		parsed, err := url.Parse(targetURL)
+		if err != nil || parsed.Host == "" {
+			outcome.Err = fmt.Errorf("invalid URL %q", targetURL)
+			continue
+		}
+		outcome.Host = parsed.Host
+		if hosts[outcome.Host] {
+			outcome.Err = fmt.Errorf("%s is already being authenticated by an earlier URL", outcome.Host)
+			continue
+		}
+		hosts[outcome.Host] = true
+	}
+
+	if len(hosts) == 0 {
+		return outcomes, nil
+	}
+
+	config, err := b.browserConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get browser config: %w", err)
+	}
+
+	driver, err := b.openDriver(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get browser: %w", err)
+	}
+	defer driver.Close()
+
+	progressf(b.progress, "Authenticating %d sites; press Enter to finish any logins still in progress\n", len(hosts))
+
+	tabs, parallel := driver.(tabDriver)
+	confirmed := b.confirmEnter()
+	var wg sync.WaitGroup
+	opened := false
+
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			continue
+		}
+
+		if !parallel {
+			b.authenticateSite(driver, outcome, config, confirmed)
+			// Enter only finishes the site it was pressed for
+			select {
+			case <-confirmed:
+				confirmed = b.confirmEnter()
+			default:
+			}
+			continue
+		}
+
+		tab := driver
+		if opened {
+			tab = tabs.Tab()
+		}
+		opened = true
+
+		wg.Add(1)
+		go func(tab BrowserDriver, outcome *AuthOutcome) {
+			defer wg.Done()
+			if tab != driver {
+				defer tab.Close()
+			}
+			b.authenticateSite(tab, outcome, config, confirmed)
+		}(tab, outcome)
+	}
+
+	wg.Wait()
+	return outcomes, nil
+}
+
+// authenticateSite runs one site's login in a tab and saves its session,
+// recording the result in outcome
+func (b *BrowserAuth) authenticateSite(driver BrowserDriver, outcome *AuthOutcome, config *BrowserConfig, confirmed <-chan struct{}) {
+	start := time.Now()
+	progressf(b.progress, "[%s] Opening %s\n", outcome.Host, outcome.URL)
+
+	if err := openPage(driver, outcome.URL, config.ReuseTab, b.progress); err != nil {
+		outcome.Err = err
+		return
+	}
+
+	b.waitForLogin(driver, outcome.Host, false, confirmed)
+
+	cookies, err := driver.Cookies()
+	if err != nil {
+		outcome.Err = fmt.Errorf("failed to extract cookies: %w", err)
+		return
+	}
+	if len(cookies) == 0 {
+		outcome.Err = fmt.Errorf("no cookies captured - login may have failed")
+		return
+	}
+
+	if err := b.sessionManager.SaveCookies(outcome.Host, cookies); err != nil {
+		outcome.Err = fmt.Errorf("failed to save cookies: %w", err)
+		return
+	}
+
+	outcome.Cookies = len(cookies)
+	outcome.Duration = time.Since(start)
+	progressf(b.progress, "[%s] Login completed; saved %d cookies\n", outcome.Host, outcome.Cookies)
+}
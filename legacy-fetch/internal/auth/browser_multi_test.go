@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuthenticateAll_WebDriver(t *testing.T) {
+	server := httptest.NewServer(&fakeWebDriver{})
+	defer server.Close()
+
+	sm := &SessionManager{cacheDir: t.TempDir()}
+	b := &BrowserAuth{
+		sessionManager:  sm,
+		pollInterval:    10 * time.Millisecond,
+		stableThreshold: 30 * time.Millisecond,
+	}
+	b.SetBrowserConfig(&BrowserConfig{
+		Type:         BrowserSafari,
+		Protocol:     ProtocolWebDriver,
+		WebDriverURL: server.URL,
+	})
+	var progress strings.Builder
+	b.SetProgress(&progress)
+
+	outcomes, err := b.AuthenticateAll([]string{
+		"https://app1.example.com/",
+		"https://app2.example.com/home",
+		"https://app1.example.com/other",
+		"not a url",
+	})
+	if err != nil {
+		t.Fatalf("AuthenticateAll failed: %v", err)
+	}
+	if len(outcomes) != 4 {
+		t.Fatalf("got %d outcomes, want one per URL", len(outcomes))
+	}
+
+	for _, o := range outcomes[:2] {
+		if o.Err != nil || o.Cookies != 1 {
+			t.Errorf("%s: Cookies = %d, Err = %v", o.URL, o.Cookies, o.Err)
+		}
+		if cookies, err := sm.LoadCookies(o.Host); err != nil || len(cookies) != 1 {
+			t.Errorf("%s: session not saved: %v, %v", o.Host, cookies, err)
+		}
+	}
+	if err := outcomes[2].Err; err == nil || !strings.Contains(err.Error(), "already being authenticated") {
+		t.Errorf("expected the repeated host to be skipped, got %v", err)
+	}
+	if outcomes[3].Err == nil {
+		t.Error("expected an invalid URL error")
+	}
+	if got := progress.String(); !strings.Contains(got, "Authenticating 2 sites") || !strings.Contains(got, "[app2.example.com] Login completed") {
+		t.Errorf("expected progress on the writer set, got %q", got)
+	}
+}
+
+// fakeTabs is a CDP browser whose tabs log in to fake sites. A site's login
+// lands back on it when its landed channel is closed; until then its tab
+// sits on the identity provider.
+type fakeTabs struct {
+	mu     sync.Mutex
+	opened []*fakeTab
+	landed map[string]chan struct{}
+}
+
+// fakeTab is one tab of fakeTabs; it implements tabDriver
+type fakeTab struct {
+	tabs   *fakeTabs
+	url    string
+	closed bool
+}
+
+func (t *fakeTab) Tab() BrowserDriver {
+	t.tabs.mu.Lock()
+	defer t.tabs.mu.Unlock()
+	tab := &fakeTab{tabs: t.tabs}
+	t.tabs.opened = append(t.tabs.opened, tab)
+	return tab
+}
+
+func (t *fakeTab) Open(targetURL string) error {
+	t.tabs.mu.Lock()
+	defer t.tabs.mu.Unlock()
+	t.url = targetURL
+	return nil
+}
+
+func (t *fakeTab) URL() (string, error) {
+	t.tabs.mu.Lock()
+	current := t.url
+	t.tabs.mu.Unlock()
+
+	parsed, _ := url.Parse(current)
+	select {
+	case <-t.tabs.landed[parsed.Host]:
+		return current, nil
+	default:
+		return "https://login.example.net/authorize", nil
+	}
+}
+
+func (t *fakeTab) Cookies() ([]*http.Cookie, error) {
+	current, _ := t.URL()
+	parsed, _ := url.Parse(current)
+	return []*http.Cookie{{Name: "session", Value: parsed.Host}}, nil
+}
+
+func (t *fakeTab) Eval(js string) (string, error) { return "", nil }
+func (t *fakeTab) Popups() ([]string, error)      { return nil, nil }
+
+func (t *fakeTab) Close() error {
+	t.tabs.mu.Lock()
+	defer t.tabs.mu.Unlock()
+	t.closed = true
+	return nil
+}
+
+func TestAuthenticateAll_ParallelTabs(t *testing.T) {
+	hosts := []string{"app1.example.com", "app2.example.com", "app3.example.com"}
+	tabs := &fakeTabs{landed: map[string]chan struct{}{}}
+	for _, host := range hosts {
+		tabs.landed[host] = make(chan struct{})
+	}
+	first := &fakeTab{tabs: tabs}
+
+	enter, pressEnter := io.Pipe()
+	sm := &SessionManager{cacheDir: t.TempDir()}
+	b := &BrowserAuth{
+		sessionManager:  sm,
+		confirm:         enter,
+		pollInterval:    10 * time.Millisecond,
+		stableThreshold: 30 * time.Millisecond,
+		newDriver:       func(*BrowserConfig) (BrowserDriver, error) { return first, nil },
+	}
+	b.SetBrowserConfig(&BrowserConfig{Type: BrowserEdge, Protocol: ProtocolCDP})
+
+	type result struct {
+		outcomes []*AuthOutcome
+		err      error
+	}
+	done := make(chan result, 1)
+	go func() {
+		outcomes, err := b.AuthenticateAll([]string{"https://app1.example.com/", "https://app2.example.com/", "https://app3.example.com/"})
+		done <- result{outcomes, err}
+	}()
+
+	saved := func(host string) bool {
+		cookies, err := sm.LoadCookies(host)
+		return err == nil && len(cookies) > 0
+	}
+	waitSaved := func(host string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for !saved(host) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: session not saved after its login completed", host)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// Logins finish in their own order, each saved as it completes
+	close(tabs.landed["app2.example.com"])
+	waitSaved("app2.example.com")
+	if saved("app1.example.com") || saved("app3.example.com") {
+		t.Error("expected only the completed login to be saved")
+	}
+	close(tabs.landed["app1.example.com"])
+	waitSaved("app1.example.com")
+	if saved("app3.example.com") {
+		t.Error("expected the login still on the identity provider not to be saved")
+	}
+	select {
+	case <-done:
+		t.Fatal("AuthenticateAll returned while a login was still in progress")
+	default:
+	}
+
+	// Enter finishes the login still running
+	if _, err := pressEnter.Write([]byte("\n")); err != nil {
+		t.Fatal(err)
+	}
+	var r result
+	select {
+	case r = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("AuthenticateAll did not return after Enter")
+	}
+	if r.err != nil {
+		t.Fatalf("AuthenticateAll failed: %v", r.err)
+	}
+	for i, o := range r.outcomes {
+		if o.Host != hosts[i] || o.Err != nil || o.Cookies != 1 {
+			t.Errorf("%s: Host = %s, Cookies = %d, Err = %v", o.URL, o.Host, o.Cookies, o.Err)
+		}
+	}
+	if !saved("app3.example.com") {
+		t.Error("expected Enter to save the unfinished login")
+	}
+
+	// The first site uses the driver's own tab; the others get their own
+	tabs.mu.Lock()
+	defer tabs.mu.Unlock()
+	if len(tabs.opened) != 2 {
+		t.Fatalf("expected a new tab for each site after the first, got %d", len(tabs.opened))
+	}
+	for i, tab := range append(tabs.opened, first) {
+		if !tab.closed {
+			t.Errorf("tab %d (%s) was not closed", i, tab.url)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// BrowserProfile is one profile inside a Chromium user-data dir
+type BrowserProfile struct {
+	Directory string // Directory under the user-data dir, e.g. "Profile 1"; the --profile-directory value
+	Name      string // Display name, e.g. "Work"
+	UserName  string // Signed-in account, if any
+	LastUsed  bool   // The profile the browser opens by default
+}
+
+// localState is the part of the user-data dir's "Local State" file that
+// describes profiles
+type localState struct {
+	Profile struct {
+		LastUsed  string `json:"last_used"`
+		InfoCache map[string]struct {
+			Name     string `json:"name"`
+			UserName string `json:"user_name"`
+		} `json:"info_cache"`
+	} `json:"profile"`
+}
+
+// ListProfiles reads the profiles of a user-data dir from its Local State file
+func ListProfiles(userDataDir string) ([]BrowserProfile, error) {
+	data, err := os.ReadFile(filepath.Join(localPath(userDataDir), "Local State"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no profiles in %s yet; start the browser once to create them", userDataDir)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Local State: %w", err)
+	}
+	return parseLocalStateProfiles(data)
+}
+
+// parseLocalStateProfiles extracts the profiles from Local State content,
+// ordered as the browser numbers them (Default, Profile 1, Profile 2, ...)
+func parseLocalStateProfiles(data []byte) ([]BrowserProfile, error) {
+	var state localState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse Local State: %w", err)
+	}
+
+	lastUsed := state.Profile.LastUsed
+	if lastUsed == "" {
+		lastUsed = "Default"
+	}
+
+	profiles := make([]BrowserProfile, 0, len(state.Profile.InfoCache))
+	for dir, info := range state.Profile.InfoCache {
+		profiles = append(profiles, BrowserProfile{
+			Directory: dir,
+			Name:      info.Name,
+			UserName:  info.UserName,
+			LastUsed:  dir == lastUsed,
+		})
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		a, b := profileOrder(profiles[i].Directory), profileOrder(profiles[j].Directory)
+		if a != b {
+			return a < b
+		}
+		return profiles[i].Directory < profiles[j].Directory
+	})
+	return profiles, nil
+}
+
+// profileOrder sorts "Default" first and "Profile N" by N
+func profileOrder(dir string) int {
+	if dir == "Default" {
+		return -1
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(dir, "Profile ")); err == nil {
+		return n
+	}
+	return int(^uint(0) >> 1)
+}
+
+// ResolveProfile finds the profile directory for a name given on the
+// command line. The directory itself, the display name and the signed-in
+// account are all accepted, case-insensitively.
+func ResolveProfile(userDataDir, name string) (string, error) {
+	profiles, err := ListProfiles(userDataDir)
+	if err != nil {
+		return "", err
+	}
+	return matchProfile(profiles, name)
+}
+
+// matchProfile picks the profile a name refers to. A directory match wins;
+// a display name or account shared by several profiles is ambiguous.
+func matchProfile(profiles []BrowserProfile, name string) (string, error) {
+	for _, p := range profiles {
+		if strings.EqualFold(p.Directory, name) {
+			return p.Directory, nil
+		}
+	}
+
+	var matches []string
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, name) || (p.UserName != "" && strings.EqualFold(p.UserName, name)) {
+			matches = append(matches, p.Directory)
+		}
+	}
+
+	switch len(matches) {
+	case 1:
+		return matches[0], nil
+	case 0:
+		names := make([]string, 0, len(profiles))
+		for _, p := range profiles {
+			names = append(names, fmt.Sprintf("%q", p.Name))
+		}
+		return "", fmt.Errorf("no browser profile named %q (available: %s)", name, strings.Join(names, ", "))
+	default:
+		return "", fmt.Errorf("browser profile %q is ambiguous (%s); use the directory name instead",
+			name, strings.Join(matches, ", "))
+	}
+}
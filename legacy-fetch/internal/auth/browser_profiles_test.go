@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testLocalState = `{
+	"browser": {"enabled_labs_experiments": []},
+	"profile": {
+		"last_used": "Profile 1",
+		"info_cache": {
+			"Profile 10": {"name": "Test tenant B", "user_name": "test-b@contoso.onmicrosoft.com"},
+			"Default": {"name": "Personal", "user_name": ""},
+			"Profile 2": {"name": "Test tenant A", "user_name": "test-a@contoso.onmicrosoft.com"},
+			"Profile 1": {"name": "Work", "user_name": "dev@omaticsoftware.com"},
+			"Profile 3": {"name": "Work", "user_name": "dev@partner.com"}
+		}
+	}
+}`
+
+func TestParseLocalStateProfiles(t *testing.T) {
+	profiles, err := parseLocalStateProfiles([]byte(testLocalState))
+	if err != nil {
+		t.Fatalf("parseLocalStateProfiles failed: %v", err)
+	}
+
+	var dirs []string
+	for _, p := range profiles {
+		dirs = append(dirs, p.Directory)
+	}
+	want := "Default,Profile 1,Profile 2,Profile 3,Profile 10"
+	if strings.Join(dirs, ",") != want {
+		t.Errorf("order = %v, want %s", dirs, want)
+	}
+
+	if !profiles[1].LastUsed || profiles[0].LastUsed {
+		t.Error("expected only Profile 1 to be marked last used")
+	}
+	if profiles[1].UserName != "dev@omaticsoftware.com" {
+		t.Errorf("UserName = %q", profiles[1].UserName)
+	}
+}
+
+func TestMatchProfile(t *testing.T) {
+	profiles, _ := parseLocalStateProfiles([]byte(testLocalState))
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "personal", want: "Default"},
+		{name: "Test tenant A", want: "Profile 2"},
+		{name: "test-b@contoso.onmicrosoft.com", want: "Profile 10"},
+		{name: "profile 3", want: "Profile 3"},
+		{name: "Work", wantErr: "ambiguous"},
+		{name: "Staging", wantErr: `available: "Personal"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchProfile(profiles, tt.name)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("matchProfile(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveProfile_FromUserDataDir(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := ResolveProfile(dir, "Work"); err == nil || !strings.Contains(err.Error(), "no profiles") {
+		t.Errorf("expected missing Local State error, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "Local State"), []byte(testLocalState), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ResolveProfile(dir, "Test tenant A")
+	if err != nil || got != "Profile 2" {
+		t.Errorf("ResolveProfile = %q, %v; want Profile 2", got, err)
+	}
+}
+
+func TestLaunchArgs_ProfileDirectory(t *testing.T) {
+	config := &BrowserConfig{UserDataDir: "/tmp/edge-debug"}
+	args := strings.Join(config.launchArgs("--remote-debugging-pipe"), " ")
+	if args != "--remote-debugging-pipe --user-data-dir=/tmp/edge-debug" {
+		t.Errorf("args = %q", args)
+	}
+
+	config.ProfileDirectory = "Profile 2"
+	args = strings.Join(config.launchArgs("--remote-debugging-pipe"), " ")
+	if !strings.HasSuffix(args, "--profile-directory=Profile 2") {
+		t.Errorf("args = %q, want --profile-directory", args)
+	}
+}
@@ -0,0 +1,331 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BrowserDefinition describes a browser fetch can drive
+type BrowserDefinition struct {
+	Name        BrowserType
+	DisplayName string
+	Protocol    Protocol // CDP when empty
+	ExePath     string   // Explicit executable; skips discovery when set
+	UserDataDir string   // Explicit debug user-data dir; platform default when empty
+	DebugPort   int      // CDP debug port, or the WebDriver server port
+
+	candidates  map[string]browserCandidates // Install locations per GOOS
+	fallbackExe string                       // Executable name used when discovery finds nothing
+	debugDirs   platformDirs                 // Default debug user-data dirs
+	userDirs    platformDirs                 // The browser's own user-data dirs, for clone-profile
+}
+
+// platformDirs names a directory per platform, relative to the platform's
+// usual per-user application data location
+type platformDirs struct {
+	Windows string // Under %LOCALAPPDATA%
+	Darwin  string // Under ~/Library/Application Support
+	Linux   string // Under $XDG_CONFIG_HOME
+}
+
+// builtinBrowsers returns the browsers fetch knows about out of the box
+func builtinBrowsers() []*BrowserDefinition {
+	return []*BrowserDefinition{
+		{
+			Name:        BrowserEdge,
+			DisplayName: "Microsoft Edge",
+			DebugPort:   9222,
+			fallbackExe: "msedge",
+			debugDirs:   platformDirs{"Microsoft/EdgeDebug", "Microsoft/EdgeDebug", "microsoft-edge-debug"},
+			userDirs:    platformDirs{"Microsoft/Edge/User Data", "Microsoft Edge", "microsoft-edge"},
+			candidates: map[string]browserCandidates{
+				"windows": {
+					Commands: []string{"msedge.exe"},
+					Paths: []string{
+						"${PROGRAMFILES(X86)}/Microsoft/Edge/Application/msedge.exe",
+						"$PROGRAMFILES/Microsoft/Edge/Application/msedge.exe",
+						"C:/Program Files (x86)/Microsoft/Edge/Application/msedge.exe",
+						"C:/Program Files/Microsoft/Edge/Application/msedge.exe",
+					},
+				},
+				"darwin": {
+					Paths: []string{
+						"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+						"~/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge",
+					},
+				},
+				"linux": {
+					Commands: []string{"microsoft-edge-stable", "microsoft-edge"},
+					Paths: []string{
+						"/opt/microsoft/msedge/msedge",
+						"/var/lib/flatpak/exports/bin/com.microsoft.Edge",
+						"~/.local/share/flatpak/exports/bin/com.microsoft.Edge",
+					},
+				},
+			},
+		},
+		{
+			Name:        BrowserChrome,
+			DisplayName: "Google Chrome",
+			DebugPort:   9223,
+			fallbackExe: "chrome",
+			debugDirs:   platformDirs{"Google/ChromeDebug", "Google/ChromeDebug", "google-chrome-debug"},
+			userDirs:    platformDirs{"Google/Chrome/User Data", "Google/Chrome", "google-chrome"},
+			candidates: map[string]browserCandidates{
+				"windows": {
+					Commands: []string{"chrome.exe"},
+					Paths: []string{
+						"$PROGRAMFILES/Google/Chrome/Application/chrome.exe",
+						"${PROGRAMFILES(X86)}/Google/Chrome/Application/chrome.exe",
+						"C:/Program Files/Google/Chrome/Application/chrome.exe",
+						"C:/Program Files (x86)/Google/Chrome/Application/chrome.exe",
+					},
+				},
+				"darwin": {
+					Paths: []string{
+						"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+						"~/Applications/Google Chrome.app/Contents/MacOS/Google Chrome",
+					},
+				},
+				"linux": {
+					Commands: []string{"google-chrome-stable", "google-chrome"},
+					Paths: []string{
+						"/opt/google/chrome/chrome",
+						"/var/lib/flatpak/exports/bin/com.google.Chrome",
+						"~/.local/share/flatpak/exports/bin/com.google.Chrome",
+					},
+				},
+			},
+		},
+		{
+			Name:        BrowserBrave,
+			DisplayName: "Brave",
+			DebugPort:   9224,
+			fallbackExe: "brave",
+			debugDirs:   platformDirs{"BraveSoftware/BraveDebug", "BraveSoftware/BraveDebug", "brave-debug"},
+			userDirs:    platformDirs{"BraveSoftware/Brave-Browser/User Data", "BraveSoftware/Brave-Browser", "BraveSoftware/Brave-Browser"},
+			candidates: map[string]browserCandidates{
+				"windows": {
+					Commands: []string{"brave.exe"},
+					Paths: []string{
+						"$PROGRAMFILES/BraveSoftware/Brave-Browser/Application/brave.exe",
+						"${PROGRAMFILES(X86)}/BraveSoftware/Brave-Browser/Application/brave.exe",
+						"$LOCALAPPDATA/BraveSoftware/Brave-Browser/Application/brave.exe",
+						"C:/Program Files/BraveSoftware/Brave-Browser/Application/brave.exe",
+					},
+				},
+				"darwin": {
+					Paths: []string{
+						"/Applications/Brave Browser.app/Contents/MacOS/Brave Browser",
+						"~/Applications/Brave Browser.app/Contents/MacOS/Brave Browser",
+					},
+				},
+				"linux": {
+					Commands: []string{"brave-browser", "brave"},
+					Paths: []string{
+						"/opt/brave.com/brave/brave",
+						"/snap/bin/brave",
+						"/var/lib/flatpak/exports/bin/com.brave.Browser",
+						"~/.local/share/flatpak/exports/bin/com.brave.Browser",
+					},
+				},
+			},
+		},
+		{
+			Name:        BrowserChromium,
+			DisplayName: "Chromium",
+			DebugPort:   9225,
+			fallbackExe: "chromium",
+			debugDirs:   platformDirs{"Chromium/ChromiumDebug", "Chromium/ChromiumDebug", "chromium-debug"},
+			userDirs:    platformDirs{"Chromium/User Data", "Chromium", "chromium"},
+			candidates: map[string]browserCandidates{
+				"windows": {
+					Commands: []string{"chromium.exe"},
+					Paths: []string{
+						"$LOCALAPPDATA/Chromium/Application/chrome.exe",
+						"$PROGRAMFILES/Chromium/Application/chrome.exe",
+					},
+				},
+				"darwin": {
+					Paths: []string{
+						"/Applications/Chromium.app/Contents/MacOS/Chromium",
+						"~/Applications/Chromium.app/Contents/MacOS/Chromium",
+					},
+				},
+				"linux": {
+					Commands: []string{"chromium", "chromium-browser"},
+					Paths: []string{
+						"/usr/lib/chromium/chromium",
+						"/snap/bin/chromium",
+						"/var/lib/flatpak/exports/bin/org.chromium.Chromium",
+						"~/.local/share/flatpak/exports/bin/org.chromium.Chromium",
+					},
+				},
+			},
+		},
+		{
+			Name:        BrowserVivaldi,
+			DisplayName: "Vivaldi",
+			DebugPort:   9226,
+			fallbackExe: "vivaldi",
+			debugDirs:   platformDirs{"Vivaldi/VivaldiDebug", "Vivaldi/VivaldiDebug", "vivaldi-debug"},
+			userDirs:    platformDirs{"Vivaldi/User Data", "Vivaldi", "vivaldi"},
+			candidates: map[string]browserCandidates{
+				"windows": {
+					Commands: []string{"vivaldi.exe"},
+					Paths: []string{
+						"$LOCALAPPDATA/Vivaldi/Application/vivaldi.exe",
+						"$PROGRAMFILES/Vivaldi/Application/vivaldi.exe",
+					},
+				},
+				"darwin": {
+					Paths: []string{
+						"/Applications/Vivaldi.app/Contents/MacOS/Vivaldi",
+						"~/Applications/Vivaldi.app/Contents/MacOS/Vivaldi",
+					},
+				},
+				"linux": {
+					Commands: []string{"vivaldi-stable", "vivaldi"},
+					Paths: []string{
+						"/opt/vivaldi/vivaldi",
+						"/var/lib/flatpak/exports/bin/com.vivaldi.Vivaldi",
+						"~/.local/share/flatpak/exports/bin/com.vivaldi.Vivaldi",
+					},
+				},
+			},
+		},
+		{
+			Name:        BrowserFirefox,
+			DisplayName: "Mozilla Firefox",
+			Protocol:    ProtocolWebDriver,
+			DebugPort:   4445,
+			fallbackExe: "geckodriver",
+			debugDirs:   platformDirs{"Mozilla/FirefoxDebug", "Firefox/FirefoxDebug", "firefox-debug"},
+			candidates: map[string]browserCandidates{
+				"windows": {Commands: []string{"geckodriver.exe"}},
+				"darwin": {
+					Commands: []string{"geckodriver"},
+					Paths:    []string{"/opt/homebrew/bin/geckodriver", "/usr/local/bin/geckodriver"},
+				},
+				"linux": {
+					Commands: []string{"geckodriver"},
+					Paths:    []string{"/snap/bin/geckodriver", "/usr/local/bin/geckodriver"},
+				},
+			},
+		},
+		{
+			Name:        BrowserSafari,
+			DisplayName: "Safari",
+			Protocol:    ProtocolWebDriver,
+			DebugPort:   4444,
+			fallbackExe: "safaridriver",
+			candidates: map[string]browserCandidates{
+				"darwin": {
+					Commands: []string{"safaridriver"},
+					Paths:    []string{"/usr/bin/safaridriver"},
+				},
+			},
+		},
+	}
+}
+
+// BrowserRegistry holds the browsers that can be selected with --browser
+type BrowserRegistry struct {
+	browsers map[BrowserType]*BrowserDefinition
+}
+
+// NewBrowserRegistry creates a registry containing the built-in browsers
+func NewBrowserRegistry() *BrowserRegistry {
+	r := &BrowserRegistry{browsers: map[BrowserType]*BrowserDefinition{}}
+	for _, def := range builtinBrowsers() {
+		r.Register(def)
+	}
+	return r
+}
+
+// LoadBrowserRegistry creates a registry from the built-in browsers plus the
+// entries in the user configuration file
+func LoadBrowserRegistry() (*BrowserRegistry, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewBrowserRegistry()
+	if err := r.Apply(config); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register adds a browser, replacing any existing entry with the same name
+func (r *BrowserRegistry) Register(def *BrowserDefinition) {
+	r.browsers[def.Name] = def
+}
+
+// Apply merges the browser entries from a config into the registry.
+// Entries naming a known browser override its non-empty fields; new entries
+// must give an exe_path and debug_port since there is nothing to discover.
+func (r *BrowserRegistry) Apply(config *Config) error {
+	for name, entry := range config.Browsers {
+		browserType := BrowserType(strings.ToLower(name))
+
+		def, ok := r.browsers[browserType]
+		if !ok {
+			if entry.ExePath == "" {
+				return fmt.Errorf("browser %q in config needs an exe_path", name)
+			}
+			if entry.DebugPort == 0 {
+				return fmt.Errorf("browser %q in config needs a debug_port", name)
+			}
+			def = &BrowserDefinition{
+				Name:        browserType,
+				DisplayName: name,
+				debugDirs:   platformDirs{"fetch/" + name + "-debug", "fetch/" + name + "-debug", "fetch/" + name + "-debug"},
+			}
+			r.Register(def)
+		}
+
+		if entry.DisplayName != "" {
+			def.DisplayName = entry.DisplayName
+		}
+		if entry.ExePath != "" {
+			def.ExePath = entry.ExePath
+		}
+		if entry.UserDataDir != "" {
+			def.UserDataDir = entry.UserDataDir
+		}
+		if entry.DebugPort != 0 {
+			def.DebugPort = entry.DebugPort
+		}
+		switch Protocol(strings.ToLower(entry.Protocol)) {
+		case "":
+		case ProtocolCDP, ProtocolWebDriver:
+			def.Protocol = Protocol(strings.ToLower(entry.Protocol))
+		default:
+			return fmt.Errorf("browser %q in config has unknown protocol %q (valid choices: cdp, webdriver)", name, entry.Protocol)
+		}
+	}
+	return nil
+}
+
+// Lookup returns the browser registered under name
+// Unknown names are an error listing the valid choices
+func (r *BrowserRegistry) Lookup(name BrowserType) (*BrowserDefinition, error) {
+	def, ok := r.browsers[BrowserType(strings.ToLower(string(name)))]
+	if !ok {
+		return nil, fmt.Errorf("unknown browser %q (valid choices: %s)", name, strings.Join(r.Names(), ", "))
+	}
+	return def, nil
+}
+
+// Names returns the registered browser names in sorted order
+func (r *BrowserRegistry) Names() []string {
+	names := make([]string, 0, len(r.browsers))
+	for name := range r.browsers {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBrowserRegistry_Builtins(t *testing.T) {
+	r := NewBrowserRegistry()
+
+	want := []string{"brave", "chrome", "chromium", "edge", "firefox", "safari", "vivaldi"}
+	got := r.Names()
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	ports := map[int]BrowserType{}
+	for _, name := range got {
+		def, err := r.Lookup(BrowserType(name))
+		if err != nil {
+			t.Fatalf("Lookup(%s) failed: %v", name, err)
+		}
+		if other, dup := ports[def.DebugPort]; dup {
+			t.Errorf("%s and %s share debug port %d", name, other, def.DebugPort)
+		}
+		ports[def.DebugPort] = def.Name
+	}
+}
+
+func TestBrowserRegistry_UnknownListsChoices(t *testing.T) {
+	_, err := NewBrowserRegistry().Lookup("opera")
+	if err == nil {
+		t.Fatal("expected error for unknown browser")
+	}
+	if !strings.Contains(err.Error(), "brave, chrome, chromium, edge, firefox, safari, vivaldi") {
+		t.Errorf("error should list valid choices, got: %v", err)
+	}
+}
+
+func TestBrowserRegistry_LookupIsCaseInsensitive(t *testing.T) {
+	def, err := NewBrowserRegistry().Lookup("Edge")
+	if err != nil {
+		t.Fatalf("Lookup(Edge) failed: %v", err)
+	}
+	if def.Name != BrowserEdge {
+		t.Errorf("Name = %s, want edge", def.Name)
+	}
+}
+
+func TestBrowserRegistry_ApplyConfig(t *testing.T) {
+	r := NewBrowserRegistry()
+	err := r.Apply(&Config{Browsers: map[string]BrowserEntryConfig{
+		"edge":    {DebugPort: 9300},
+		"thorium": {ExePath: "/usr/bin/thorium", DebugPort: 9230},
+	}})
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	edge, _ := r.Lookup(BrowserEdge)
+	if edge.DebugPort != 9300 {
+		t.Errorf("edge DebugPort = %d, want override 9300", edge.DebugPort)
+	}
+	if edge.DisplayName != "Microsoft Edge" {
+		t.Errorf("edge DisplayName = %q, empty override should keep built-in", edge.DisplayName)
+	}
+
+	thorium, err := r.Lookup("thorium")
+	if err != nil {
+		t.Fatalf("Lookup(thorium) failed: %v", err)
+	}
+	config := fakeDiscoveryEnv("linux", nil).browserConfig(thorium)
+	if config.ExePath != "/usr/bin/thorium" || config.ExeReason != "set in config" {
+		t.Errorf("thorium exe = %q (%s), want configured path", config.ExePath, config.ExeReason)
+	}
+	if config.UserDataDir != "/home/dev/.config/fetch/thorium-debug" {
+		t.Errorf("thorium UserDataDir = %q", config.UserDataDir)
+	}
+}
+
+func TestBrowserRegistry_ApplyRejectsIncompleteEntry(t *testing.T) {
+	err := NewBrowserRegistry().Apply(&Config{Browsers: map[string]BrowserEntryConfig{
+		"thorium": {DebugPort: 9230},
+	}})
+	if err == nil {
+		t.Fatal("expected error for new browser without exe_path")
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := loadConfigFile(filepath.Join(dir, "missing.json"))
+	if err != nil {
+		t.Fatalf("missing config should not error: %v", err)
+	}
+	if len(config.Browsers) != 0 {
+		t.Errorf("expected empty config, got %+v", config)
+	}
+
+	path := filepath.Join(dir, "fetch.json")
+	content := `{"browsers": {"brave": {"exe_path": "/opt/brave/brave"}}}`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	config, err = loadConfigFile(path)
+	if err != nil {
+		t.Fatalf("loadConfigFile failed: %v", err)
+	}
+	if config.Browsers["brave"].ExePath != "/opt/brave/brave" {
+		t.Errorf("brave exe_path = %q", config.Browsers["brave"].ExePath)
+	}
+}
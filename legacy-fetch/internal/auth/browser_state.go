@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// LaunchedBrowser records a browser process that fetch started itself
+type LaunchedBrowser struct {
+	Type        BrowserType `json:"type"`
+	PID         int         `json:"pid"`
+	Port        int         `json:"port"`
+	ExePath     string      `json:"exe_path"`
+	UserDataDir string      `json:"user_data_dir"`
+	StartedAt   time.Time   `json:"started_at"`
+
+	// ProcessStart is when the process at PID started, as the OS reports
+	// it. It tells the browser apart from a process that reused its PID
+	// after the browser exited or the machine rebooted.
+	ProcessStart time.Time `json:"process_start,omitempty"`
+
+	// Interop is set for Windows browsers launched from WSL. PID is then the
+	// WSL interop shim, so liveness is checked by probing DebugURL instead.
+	Interop  bool   `json:"interop,omitempty"`
+	DebugURL string `json:"debug_url,omitempty"`
+}
+
+// processStartTolerance absorbs the rounding of process start times, which
+// some platforms only report to the second
+const processStartTolerance = 2 * time.Second
+
+// Alive reports whether the recorded browser process is still running
+func (l *LaunchedBrowser) Alive() bool {
+	if l.Interop {
+		return debugEndpointResponds(l.DebugURL)
+	}
+	if !processAlive(l.PID) {
+		return false
+	}
+	// Records written before start times were kept cannot be told apart
+	return l.ProcessStart.IsZero() || l.SameProcess()
+}
+
+// SameProcess reports whether the process now running at PID is the one
+// fetch launched, by comparing its start time with the recorded one
+func (l *LaunchedBrowser) SameProcess() bool {
+	if l.ProcessStart.IsZero() {
+		return false
+	}
+	start, err := processStartTime(l.PID)
+	if err != nil {
+		return false
+	}
+	diff := start.Sub(l.ProcessStart)
+	return diff <= processStartTolerance && diff >= -processStartTolerance
+}
+
+// BrowserStateStore persists launched browsers so later runs can reattach to
+// or shut down what an earlier run started
+type BrowserStateStore struct {
+	path string // State file (e.g., ~/.omatic/browsers.json)
+}
+
+// NewBrowserStateStore creates a BrowserStateStore using the default state file
+func NewBrowserStateStore() (*BrowserStateStore, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+
+	stateDir := filepath.Join(homeDir, ".omatic")
+	if err := os.MkdirAll(stateDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	return &BrowserStateStore{
+		path: filepath.Join(stateDir, "browsers.json"),
+	}, nil
+}
+
+// load reads all launched browsers keyed by browser type
+// Returns an empty map if the state file does not exist yet (not an error)
+func (s *BrowserStateStore) load() (map[BrowserType]*LaunchedBrowser, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return map[BrowserType]*LaunchedBrowser{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read browser state: %w", err)
+	}
+
+	browsers := map[BrowserType]*LaunchedBrowser{}
+	if err := json.Unmarshal(data, &browsers); err != nil {
+		return nil, fmt.Errorf("failed to parse browser state: %w", err)
+	}
+	return browsers, nil
+}
+
+// write replaces the state file with the given browsers
+func (s *BrowserStateStore) write(browsers map[BrowserType]*LaunchedBrowser) error {
+	data, err := json.MarshalIndent(browsers, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal browser state: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write browser state: %w", err)
+	}
+	return nil
+}
+
+// Get returns the recorded browser for a type, or nil if none was recorded
+func (s *BrowserStateStore) Get(browserType BrowserType) (*LaunchedBrowser, error) {
+	browsers, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return browsers[browserType], nil
+}
+
+// List returns all recorded browsers sorted by type
+func (s *BrowserStateStore) List() ([]*LaunchedBrowser, error) {
+	browsers, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]*LaunchedBrowser, 0, len(browsers))
+	for _, b := range browsers {
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Type < list[j].Type })
+	return list, nil
+}
+
+// Save records a launched browser, replacing any earlier record for its type
+func (s *BrowserStateStore) Save(launched *LaunchedBrowser) error {
+	browsers, err := s.load()
+	if err != nil {
+		return err
+	}
+	browsers[launched.Type] = launched
+	return s.write(browsers)
+}
+
+// Remove forgets the recorded browser for a type
+func (s *BrowserStateStore) Remove(browserType BrowserType) error {
+	browsers, err := s.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := browsers[browserType]; !ok {
+		return nil
+	}
+	delete(browsers, browserType)
+	return s.write(browsers)
+}
+
+// Prune removes records whose processes are no longer running
+// Returns the number of records removed
+func (s *BrowserStateStore) Prune() (int, error) {
+	browsers, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for browserType, b := range browsers {
+		if b.Alive() {
+			continue
+		}
+		delete(browsers, browserType)
+		pruned++
+	}
+
+	if pruned > 0 {
+		if err := s.write(browsers); err != nil {
+			return 0, err
+		}
+	}
+	return pruned, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBrowserStateStore_SaveGetRemove(t *testing.T) {
+	store := &BrowserStateStore{path: filepath.Join(t.TempDir(), "browsers.json")}
+
+	launched := &LaunchedBrowser{
+		Type:        BrowserEdge,
+		PID:         os.Getpid(),
+		Port:        9222,
+		ExePath:     "/usr/bin/microsoft-edge",
+		UserDataDir: "/tmp/edge-debug",
+		StartedAt:   time.Now().Truncate(time.Second),
+	}
+
+	if err := store.Save(launched); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	got, err := store.Get(BrowserEdge)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got == nil {
+		t.Fatal("expected a recorded edge browser")
+	}
+	if got.PID != launched.PID || got.Port != launched.Port || got.UserDataDir != launched.UserDataDir {
+		t.Errorf("Get() = %+v, want %+v", got, launched)
+	}
+	if !got.StartedAt.Equal(launched.StartedAt) {
+		t.Errorf("StartedAt = %v, want %v", got.StartedAt, launched.StartedAt)
+	}
+
+	if err := store.Remove(BrowserEdge); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	got, err = store.Get(BrowserEdge)
+	if err != nil {
+		t.Fatalf("Get after Remove failed: %v", err)
+	}
+	if got != nil {
+		t.Errorf("expected no record after Remove, got %+v", got)
+	}
+}
+
+func TestBrowserStateStore_MissingFile(t *testing.T) {
+	store := &BrowserStateStore{path: filepath.Join(t.TempDir(), "browsers.json")}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List should not error on missing file: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("expected empty list, got %d entries", len(list))
+	}
+
+	// Removing from an empty store is not an error
+	if err := store.Remove(BrowserChrome); err != nil {
+		t.Errorf("Remove should not error on missing file: %v", err)
+	}
+}
+
+func TestBrowserStateStore_PruneDeadProcesses(t *testing.T) {
+	store := &BrowserStateStore{path: filepath.Join(t.TempDir(), "browsers.json")}
+
+	// A process that has already exited and been reaped
+	cmd := exec.Command("go", "version")
+	if err := cmd.Run(); err != nil {
+		t.Skipf("cannot run helper process: %v", err)
+	}
+	deadPID := cmd.Process.Pid
+
+	if err := store.Save(&LaunchedBrowser{Type: BrowserEdge, PID: os.Getpid(), Port: 9222}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := store.Save(&LaunchedBrowser{Type: BrowserChrome, PID: deadPID, Port: 9223}); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	pruned, err := store.Prune()
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("expected 1 pruned record, got %d", pruned)
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 1 || list[0].Type != BrowserEdge {
+		t.Errorf("expected only the live edge record to remain, got %+v", list)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) {
+		t.Error("expected current process to be alive")
+	}
+	if processAlive(0) {
+		t.Error("expected PID 0 to be reported as not alive")
+	}
+}
+
+func TestLaunchedBrowser_SameProcess(t *testing.T) {
+	start, err := processStartTime(os.Getpid())
+	if err != nil {
+		t.Skipf("cannot read process start time: %v", err)
+	}
+	if since := time.Since(start); since < 0 || since > time.Hour {
+		t.Errorf("start time %v is not when the test process started", start)
+	}
+
+	launched := &LaunchedBrowser{Type: BrowserEdge, PID: os.Getpid(), ProcessStart: start}
+	if !launched.SameProcess() || !launched.Alive() {
+		t.Error("expected the recorded process to be confirmed")
+	}
+
+	// The PID now belongs to a process that started at another time
+	reused := &LaunchedBrowser{Type: BrowserEdge, PID: os.Getpid(), ProcessStart: start.Add(-time.Hour)}
+	if reused.SameProcess() || reused.Alive() {
+		t.Error("expected a reused PID not to count as the browser")
+	}
+
+	// Without a recorded start time the process cannot be confirmed
+	legacy := &LaunchedBrowser{Type: BrowserEdge, PID: os.Getpid()}
+	if legacy.SameProcess() || !legacy.Alive() {
+		t.Error("expected an old record to be alive but unconfirmed")
+	}
+}
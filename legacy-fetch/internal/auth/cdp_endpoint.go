@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/cdp"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// DefaultConnectTimeout bounds how long connecting to a CDP endpoint may take
+const DefaultConnectTimeout = 10 * time.Second
+
+// ResolveCDPEndpoint turns a CDP endpoint into a browser WebSocket URL.
+// http(s)://host:port is treated as a discovery endpoint and queried at
+// /json/version; ws(s):// URLs are used as given. A bare host:port is
+// treated as http.
+func ResolveCDPEndpoint(endpoint string, timeout time.Duration) (string, error) {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid CDP URL %q: %w", endpoint, err)
+	}
+
+	switch u.Scheme {
+	case "ws", "wss":
+		if strings.Contains(u.Path, "/devtools/page/") {
+			return "", fmt.Errorf("%s is a page target, not a browser - use the webSocketDebuggerUrl from /json/version", endpoint)
+		}
+		return endpoint, nil
+	case "http", "https":
+		return discoverWebSocketURL(u, timeout)
+	default:
+		return "", fmt.Errorf("unsupported CDP URL scheme %q (use http://host:port or ws://...)", u.Scheme)
+	}
+}
+
+// discoverWebSocketURL queries a discovery endpoint's /json/version for the
+// browser WebSocket URL
+func discoverWebSocketURL(u *url.URL, timeout time.Duration) (string, error) {
+	versionURL := strings.TrimSuffix(u.String(), "/") + "/json/version"
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(versionURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach CDP endpoint %s: %w", u.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s is not a browser DevTools endpoint: /json/version returned %s", u.Host, resp.Status)
+	}
+
+	var result struct {
+		WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil || result.WebSocketDebuggerURL == "" {
+		return "", fmt.Errorf("%s is not a browser DevTools endpoint: no webSocketDebuggerUrl in /json/version", u.Host)
+	}
+
+	return rewriteWebSocketHost(result.WebSocketDebuggerURL, u), nil
+}
+
+// rewriteWebSocketHost points a browser-reported WebSocket URL at the host we
+// actually reached. Browsers behind port forwards or in containers report
+// their own view of the address (often 127.0.0.1 or 0.0.0.0), which is not
+// reachable from here.
+func rewriteWebSocketHost(wsURL string, discovery *url.URL) string {
+	u, err := url.Parse(wsURL)
+	if err != nil {
+		return wsURL
+	}
+	u.Host = discovery.Host
+	if discovery.Scheme == "https" {
+		u.Scheme = "wss"
+	}
+	return u.String()
+}
+
+// connectCDP dials a browser WebSocket URL and confirms a browser answers
+// before handing back a connected rod.Browser
+func connectCDP(wsURL string, timeout time.Duration) (*rod.Browser, error) {
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// rod only applies the context to the dial, not to reading the handshake
+	// response, so enforce the timeout around the whole connect
+	type dialResult struct {
+		ws  *cdp.WebSocket
+		err error
+	}
+	dialed := make(chan dialResult, 1)
+	go func() {
+		ws := &cdp.WebSocket{}
+		err := ws.Connect(ctx, wsURL, nil)
+		dialed <- dialResult{ws, err}
+	}()
+
+	var ws *cdp.WebSocket
+	select {
+	case result := <-dialed:
+		if result.err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", wsURL, result.err)
+		}
+		ws = result.ws
+	case <-ctx.Done():
+		// A handshake that completes after the timeout would leave its
+		// connection open, so close it whenever it arrives
+		go func() {
+			if result := <-dialed; result.err == nil {
+				_ = result.ws.Close()
+			}
+		}()
+		return nil, fmt.Errorf("timed out connecting to %s after %s", wsURL, timeout)
+	}
+
+	browser := rod.New().Client(cdp.New().Start(ws))
+
+	version, err := proto.BrowserGetVersion{}.Call(browser.Context(ctx))
+	if err != nil || version.Product == "" {
+		_ = ws.Close()
+		return nil, fmt.Errorf("%s did not answer as a browser within %s", wsURL, timeout)
+	}
+
+	if err := browser.Connect(); err != nil {
+		_ = ws.Close()
+		return nil, fmt.Errorf("failed to attach to browser: %w", err)
+	}
+
+	return browser, nil
+}
+
+// ConnectCDPEndpoint resolves and connects to an arbitrary CDP endpoint
+func ConnectCDPEndpoint(endpoint string, timeout time.Duration) (*rod.Browser, error) {
+	if timeout <= 0 {
+		timeout = DefaultConnectTimeout
+	}
+
+	wsURL, err := ResolveCDPEndpoint(endpoint, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return connectCDP(wsURL, timeout)
+}
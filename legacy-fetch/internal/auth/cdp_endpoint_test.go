@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveCDPEndpoint_Discovery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/json/version" {
+			http.NotFound(w, r)
+			return
+		}
+		// Browsers in containers report their own bind address
+		w.Write([]byte(`{"Browser":"HeadlessChrome/120.0","webSocketDebuggerUrl":"ws://0.0.0.0:9222/devtools/browser/abc"}`))
+	}))
+	defer server.Close()
+
+	wsURL, err := ResolveCDPEndpoint(server.URL, time.Second)
+	if err != nil {
+		t.Fatalf("ResolveCDPEndpoint failed: %v", err)
+	}
+
+	serverURL, _ := url.Parse(server.URL)
+	want := "ws://" + serverURL.Host + "/devtools/browser/abc"
+	if wsURL != want {
+		t.Errorf("wsURL = %q, want %q", wsURL, want)
+	}
+
+	// A bare host:port is treated as a discovery endpoint
+	wsURL, err = ResolveCDPEndpoint(serverURL.Host, time.Second)
+	if err != nil {
+		t.Fatalf("ResolveCDPEndpoint(host:port) failed: %v", err)
+	}
+	if wsURL != want {
+		t.Errorf("wsURL = %q, want %q", wsURL, want)
+	}
+}
+
+func TestResolveCDPEndpoint_NotABrowser(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{
+			name: "404",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.NotFound(w, r)
+			},
+		},
+		{
+			name: "HTML page",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("<html>hello</html>"))
+			},
+		},
+		{
+			name: "JSON without webSocketDebuggerUrl",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"status":"ok"}`))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+
+			_, err := ResolveCDPEndpoint(server.URL, time.Second)
+			if err == nil {
+				t.Fatal("expected error for non-browser endpoint")
+			}
+			if !strings.Contains(err.Error(), "not a browser") {
+				t.Errorf("error should say it is not a browser, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestResolveCDPEndpoint_WebSocketURLs(t *testing.T) {
+	wsURL, err := ResolveCDPEndpoint("ws://10.0.0.5:9222/devtools/browser/abc", time.Second)
+	if err != nil {
+		t.Fatalf("ResolveCDPEndpoint failed: %v", err)
+	}
+	if wsURL != "ws://10.0.0.5:9222/devtools/browser/abc" {
+		t.Errorf("browser ws URL should be used as given, got %q", wsURL)
+	}
+
+	if _, err := ResolveCDPEndpoint("ws://10.0.0.5:9222/devtools/page/123", time.Second); err == nil {
+		t.Error("expected error for page target URL")
+	}
+
+	if _, err := ResolveCDPEndpoint("ftp://10.0.0.5:9222", time.Second); err == nil {
+		t.Error("expected error for unsupported scheme")
+	}
+}
+
+func TestConnectCDP_TimesOutOnNonBrowser(t *testing.T) {
+	// Accepts the connection but never completes a WebSocket handshake
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second)
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+
+	start := time.Now()
+	_, err := connectCDP("ws://"+serverURL.Host+"/devtools/browser/abc", 300*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected error connecting to non-browser")
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Errorf("connect took %s, expected it to respect the timeout", elapsed)
+	}
+}
+
+func TestConnectCDP_ClosesLateConnection(t *testing.T) {
+	closed := make(chan struct{})
+	// Completes the WebSocket handshake only after the connect timeout,
+	// then waits for the client to hang up
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		time.Sleep(500 * time.Millisecond)
+
+		sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+		buf.Flush()
+
+		conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+		if _, err := io.Copy(io.Discard, conn); err == nil {
+			close(closed) // EOF: the client closed the connection
+		}
+	}))
+	defer server.Close()
+
+	serverURL, _ := url.Parse(server.URL)
+	if _, err := connectCDP("ws://"+serverURL.Host+"/devtools/browser/abc", 200*time.Millisecond); err == nil {
+		t.Fatal("expected a timeout")
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(4 * time.Second):
+		t.Error("expected the connection completed after the timeout to be closed")
+	}
+}
@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/cdp"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// LaunchMode is how fetch exposes CDP on a browser it launches itself
+type LaunchMode string
+
+const (
+	// LaunchPipe speaks CDP over --remote-debugging-pipe. No network
+	// listener exists, so no other local process or web page can drive the
+	// logged-in browser, but the browser lives only as long as fetch.
+	LaunchPipe LaunchMode = "pipe"
+	// LaunchPort opens --remote-debugging-port on localhost so the browser
+	// can outlive fetch and be reattached to (fetch browser start)
+	LaunchPort LaunchMode = "port"
+)
+
+// pipeStartTimeout bounds waiting for a pipe-launched browser to answer
+const pipeStartTimeout = 15 * time.Second
+
+// ParseLaunchMode validates a launch mode name; empty means the default
+func ParseLaunchMode(name string) (LaunchMode, error) {
+	switch mode := LaunchMode(strings.ToLower(name)); mode {
+	case "", LaunchPipe, LaunchPort:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown launch mode %q (valid choices: pipe, port)", name)
+	}
+}
+
+// resolveLaunchMode picks the launch mode for a browser fetch launches.
+// Pipe is the default; it needs inherited file descriptors, which Windows
+// and Windows executables started from WSL cannot receive, so those fall
+// back to port mode unless pipe was asked for explicitly.
+func resolveLaunchMode(config *BrowserConfig) (LaunchMode, error) {
+	supported := pipeSupported && !isInteropExe(config.ExePath)
+	switch config.LaunchMode {
+	case "":
+		if supported {
+			return LaunchPipe, nil
+		}
+		return LaunchPort, nil
+	case LaunchPipe:
+		if !supported {
+			return "", fmt.Errorf("pipe launch mode is not supported for %s here; use --launch-mode port", config.ExePath)
+		}
+	}
+	return config.LaunchMode, nil
+}
+
+// isInteropExe reports whether exe is a Windows program run through WSL interop
+func isInteropExe(exe string) bool {
+	return IsWSL() && strings.HasSuffix(strings.ToLower(exe), ".exe")
+}
+
+// pipeTransport carries CDP messages over the browser's debugging pipe.
+// Messages are JSON, each terminated by a NUL byte.
+type pipeTransport struct {
+	mu     sync.Mutex
+	writer io.Writer
+	reader *bufio.Reader
+}
+
+// newPipeTransport creates a transport writing to and reading from the browser
+func newPipeTransport(toBrowser io.Writer, fromBrowser io.Reader) *pipeTransport {
+	return &pipeTransport{writer: toBrowser, reader: bufio.NewReader(fromBrowser)}
+}
+
+// Send writes one message to the browser
+func (p *pipeTransport) Send(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	message := make([]byte, 0, len(data)+1)
+	message = append(append(message, data...), 0)
+	_, err := p.writer.Write(message)
+	return err
+}
+
+// Read returns the next message from the browser
+func (p *pipeTransport) Read() ([]byte, error) {
+	message, err := p.reader.ReadBytes(0)
+	if err != nil {
+		return nil, err
+	}
+	return message[:len(message)-1], nil
+}
+
+// launchPipeBrowser starts the browser with CDP on --remote-debugging-pipe and
+// connects to it. The browser reads commands from fd 3 and writes to fd 4.
+// It is not detached or recorded in the state file: closing the returned
+// browser, or fetch exiting, ends it.
+func launchPipeBrowser(config *BrowserConfig) (*rod.Browser, error) {
+	// Browser reads commands from browserIn, we read its replies from ourIn
+	browserIn, ourOut, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create debugging pipe: %w", err)
+	}
+	ourIn, browserOut, err := os.Pipe()
+	if err != nil {
+		browserIn.Close()
+		ourOut.Close()
+		return nil, fmt.Errorf("failed to create debugging pipe: %w", err)
+	}
+
+	cmd := exec.Command(config.ExePath, config.launchArgs("--remote-debugging-pipe")...)
+	cmd.ExtraFiles = []*os.File{browserIn, browserOut}
+	err = cmd.Start()
+
+	// The browser holds its own copies of its ends now
+	browserIn.Close()
+	browserOut.Close()
+
+	if err != nil {
+		ourIn.Close()
+		ourOut.Close()
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+
+	// Reap the process and release our ends once the browser exits
+	go func() {
+		_ = cmd.Wait()
+		ourIn.Close()
+		ourOut.Close()
+	}()
+
+	client := cdp.New().Start(newPipeTransport(ourOut, ourIn))
+	browser := rod.New().Client(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), pipeStartTimeout)
+	defer cancel()
+
+	version, err := proto.BrowserGetVersion{}.Call(browser.Context(ctx))
+	if err != nil || version.Product == "" {
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("browser did not answer on the debugging pipe within %s", pipeStartTimeout)
+	}
+
+	if err := browser.Connect(); err != nil {
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("failed to attach to browser: %w", err)
+	}
+
+	return browser, nil
+}
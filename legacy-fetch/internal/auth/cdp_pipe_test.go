@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/cdp"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestPipeTransport_Framing(t *testing.T) {
+	var sent bytes.Buffer
+	incoming := bytes.NewBufferString(`{"id":1}` + "\x00" + `{"method":"Target.targetCreated"}` + "\x00")
+	transport := newPipeTransport(&sent, incoming)
+
+	if err := transport.Send([]byte(`{"id":1,"method":"Browser.getVersion"}`)); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if sent.String() != `{"id":1,"method":"Browser.getVersion"}`+"\x00" {
+		t.Errorf("sent %q, want message terminated by NUL", sent.String())
+	}
+
+	for _, want := range []string{`{"id":1}`, `{"method":"Target.targetCreated"}`} {
+		got, err := transport.Read()
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("Read() = %q, want %q", got, want)
+		}
+	}
+
+	if _, err := transport.Read(); err != io.EOF {
+		t.Errorf("expected EOF after the last message, got %v", err)
+	}
+}
+
+func TestPipeTransport_RodClient(t *testing.T) {
+	toBrowserR, toBrowserW := io.Pipe()
+	fromBrowserR, fromBrowserW := io.Pipe()
+	defer toBrowserW.Close()
+	defer fromBrowserW.Close()
+
+	// Fake browser: answer Browser.getVersion on the pipe
+	go func() {
+		reader := bufio.NewReader(toBrowserR)
+		for {
+			message, err := reader.ReadBytes(0)
+			if err != nil {
+				return
+			}
+			var req struct {
+				ID     int    `json:"id"`
+				Method string `json:"method"`
+			}
+			json.Unmarshal(message[:len(message)-1], &req)
+
+			reply, _ := json.Marshal(map[string]interface{}{
+				"id":     req.ID,
+				"result": map[string]string{"product": "Chrome/130.0.0.0", "protocolVersion": "1.3"},
+			})
+			fromBrowserW.Write(append(reply, 0))
+		}
+	}()
+
+	client := cdp.New().Start(newPipeTransport(toBrowserW, fromBrowserR))
+	browser := rod.New().Client(client)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	version, err := proto.BrowserGetVersion{}.Call(browser.Context(ctx))
+	if err != nil {
+		t.Fatalf("Browser.getVersion over pipe failed: %v", err)
+	}
+	if version.Product != "Chrome/130.0.0.0" {
+		t.Errorf("Product = %q", version.Product)
+	}
+}
+
+func TestParseLaunchMode(t *testing.T) {
+	for name, want := range map[string]LaunchMode{"": "", "pipe": LaunchPipe, "PORT": LaunchPort} {
+		got, err := ParseLaunchMode(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLaunchMode(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := ParseLaunchMode("socket"); err == nil {
+		t.Error("expected error for unknown launch mode")
+	}
+}
+
+func TestResolveLaunchMode(t *testing.T) {
+	config := &BrowserConfig{ExePath: "/usr/bin/chromium"}
+	mode, err := resolveLaunchMode(config)
+	if err != nil {
+		t.Fatalf("resolveLaunchMode failed: %v", err)
+	}
+	want := LaunchPort
+	if pipeSupported {
+		want = LaunchPipe
+	}
+	if mode != want {
+		t.Errorf("default mode = %q, want %q", mode, want)
+	}
+
+	config.LaunchMode = LaunchPort
+	if mode, _ := resolveLaunchMode(config); mode != LaunchPort {
+		t.Errorf("explicit port mode = %q, want port", mode)
+	}
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// cognitoPrefix starts the localStorage keys of the AWS Amplify / Cognito
+// Identity SDK: "CognitoIdentityServiceProvider.<client id>.LastAuthUser"
+// names the signed-in user, whose tokens are under
+// "CognitoIdentityServiceProvider.<client id>.<username>.accessToken" (and
+// .idToken, .refreshToken)
+const cognitoPrefix = "CognitoIdentityServiceProvider."
+
+// cognitoExtractor reads the tokens the Cognito SDK keeps in localStorage
+type cognitoExtractor struct{}
+
+func (cognitoExtractor) Name() string { return "cognito" }
+
+// Extract returns the access token of a client's last signed-in user.
+// --audience selects the app client ID, since Cognito access tokens carry
+// client_id instead of aud.
+func (e cognitoExtractor) Extract(result *AuthResult, query TokenQuery) (*ExtractedToken, error) {
+	all, problems := e.List(result)
+	if len(all) == 0 {
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("no usable Cognito token in localStorage: %w", problems[0])
+		}
+		return nil, ErrNoToken
+	}
+
+	var clients []string
+	for _, token := range all {
+		if (query.Audience == "" || token.Audience == query.Audience) && hasScopes(token.Scope, query.Scope) {
+			return token, nil
+		}
+		clients = append(clients, token.Audience)
+	}
+	return nil, fmt.Errorf("no Cognito token for the app client and scope (available clients: %s)", strings.Join(clients, ", "))
+}
+
+// List returns the access token of each app client's last signed-in user
+func (cognitoExtractor) List(result *AuthResult) ([]*ExtractedToken, []error) {
+	var all []*ExtractedToken
+	var problems []error
+
+	for _, key := range sortedKeys(result.LocalStorage) {
+		if !strings.HasPrefix(key, cognitoPrefix) || !strings.HasSuffix(key, ".LastAuthUser") {
+			continue
+		}
+		clientID := strings.TrimSuffix(strings.TrimPrefix(key, cognitoPrefix), ".LastAuthUser")
+		username := jsonString(result.LocalStorage[key])
+		tokenKey := cognitoPrefix + clientID + "." + username + ".accessToken"
+
+		raw := jsonString(result.LocalStorage[tokenKey])
+		if raw == "" {
+			problems = append(problems, fmt.Errorf("Cognito user %q of client %s has no access token", username, clientID))
+			continue
+		}
+
+		token := &ExtractedToken{
+			Provider: "cognito",
+			Token:    raw,
+			Source:   "localStorage " + tokenKey,
+			Audience: clientID,
+			Subject:  username,
+		}
+		if jwt, err := DecodeJWT(raw); err == nil {
+			token.Scope = jwt.StringClaim("scope")
+			if exp, ok := jwt.TimeClaim("exp"); ok {
+				token.ExpiresAt = exp
+			}
+		} else {
+			problems = append(problems, fmt.Errorf("Cognito access token of %q is not a JWT: %w", username, err))
+			continue
+		}
+		all = append(all, token)
+	}
+	return all, problems
+}
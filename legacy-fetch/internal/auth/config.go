@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the user configuration read from ~/.omatic/fetch.json
+type Config struct {
+	// Browsers adds registry entries or overrides fields of built-in ones, keyed by name
+	Browsers map[string]BrowserEntryConfig `json:"browsers"`
+
+	// CDPURL connects to this endpoint instead of launching a local browser.
+	// Either an http://host:port discovery endpoint or a ws:// browser URL.
+	CDPURL string `json:"cdp_url"`
+
+	// ConnectTimeout bounds connecting to the browser, as a Go duration (e.g. "10s")
+	ConnectTimeout string `json:"connect_timeout"`
+
+	// LaunchMode is "pipe" (default) or "port"; see LaunchMode
+	LaunchMode string `json:"launch_mode"`
+
+	// ReuseTab logs in through an open tab on the target's origin instead of a new one
+	ReuseTab bool `json:"reuse_tab"`
+}
+
+// BrowserEntryConfig is a browser registry entry as written in the config file.
+// Empty fields keep the built-in value.
+type BrowserEntryConfig struct {
+	DisplayName string `json:"display_name"`
+	ExePath     string `json:"exe_path"`
+	UserDataDir string `json:"user_data_dir"`
+	DebugPort   int    `json:"debug_port"`
+	Protocol    string `json:"protocol"` // "cdp" (default) or "webdriver"
+}
+
+// DefaultConfigPath returns the path of the user configuration file
+func DefaultConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".omatic", "fetch.json"), nil
+}
+
+// LoadConfig reads the user configuration file
+// Returns an empty config if the file does not exist (not an error)
+func LoadConfig() (*Config, error) {
+	path, err := DefaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return loadConfigFile(path)
+}
+
+// loadConfigFile reads a configuration file from an explicit path
+func loadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &config, nil
+}
@@ -0,0 +1,327 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// BrowserDriver is the browser automation a login flow needs. It is
+// implemented over CDP for Chromium browsers and over W3C WebDriver for
+// browsers without CDP, such as Safari (safaridriver) or Firefox (geckodriver).
+type BrowserDriver interface {
+	// Open opens a new page (tab or window) at the URL
+	Open(targetURL string) error
+	// URL returns the opened page's current URL
+	URL() (string, error)
+	// Cookies returns the cookies the browser holds for the opened page
+	Cookies() ([]*http.Cookie, error)
+	// Eval runs a JS function expression, e.g. `() => document.title`,
+	// on the opened page and returns its result, which must be a string
+	Eval(js string) (string, error)
+	// Popups returns the current URLs of the open windows the opened page
+	// started, directly or from another such window, e.g. a loginPopup
+	Popups() ([]string, error)
+	// Close closes the opened page and releases the driver
+	Close() error
+}
+
+// cdpDriver implements BrowserDriver over the Chrome DevTools Protocol
+type cdpDriver struct {
+	browser      *rod.Browser
+	page         *rod.Page
+	closeBrowser bool // Close the whole browser on Close, not just the page
+	reused       bool // page is a tab the user had open, left open on Close
+	silent       bool // Open pages out of sight; see openSilent
+
+	popups map[proto.TargetTargetID]bool // Targets opened from page, including closed ones
+}
+
+// newCDPDriver wraps a connected rod browser
+func newCDPDriver(browser *rod.Browser, closeBrowser bool) *cdpDriver {
+	return &cdpDriver{browser: browser, closeBrowser: closeBrowser}
+}
+
+// Open opens a new tab at the URL
+func (d *cdpDriver) Open(targetURL string) error {
+	if d.silent {
+		return d.openSilent(targetURL)
+	}
+	page, err := d.browser.Page(proto.TargetCreateTarget{URL: targetURL})
+	if err != nil {
+		return fmt.Errorf("failed to open page: %w", err)
+	}
+	d.page = page
+	return nil
+}
+
+// Tab returns a driver for another tab over the same browser connection
+func (d *cdpDriver) Tab() BrowserDriver {
+	return newCDPDriver(d.browser, false)
+}
+
+// Reuse makes an open tab on the target URL's origin the driver's page,
+// preferring one on the exact URL. It reports false when there is none.
+func (d *cdpDriver) Reuse(targetURL string) (bool, error) {
+	pages, err := d.browser.Pages()
+	if err != nil {
+		return false, fmt.Errorf("failed to list tabs: %w", err)
+	}
+
+	var urls []string
+	for _, p := range pages {
+		info, err := p.Info()
+		if err != nil || info.Type != proto.TargetTargetInfoTypePage {
+			urls = append(urls, "")
+			continue
+		}
+		urls = append(urls, info.URL)
+	}
+
+	i := matchTab(urls, targetURL)
+	if i < 0 {
+		return false, nil
+	}
+	d.page, d.reused = pages[i], true
+	_, _ = d.page.Activate()
+	return true, nil
+}
+
+// matchTab returns the index of the tab URL that is targetURL, or failing
+// that the first on its origin (scheme and host). Returns -1 if none is.
+func matchTab(urls []string, targetURL string) int {
+	target, err := url.Parse(targetURL)
+	if err != nil {
+		return -1
+	}
+
+	match := -1
+	for i, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Scheme != target.Scheme || parsed.Host != target.Host {
+			continue
+		}
+		if u == targetURL {
+			return i
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	return match
+}
+
+// URL returns the tab's current URL
+func (d *cdpDriver) URL() (string, error) {
+	info, err := d.page.Info()
+	if err != nil {
+		return "", err
+	}
+	return info.URL, nil
+}
+
+// Cookies returns every cookie in the browser, not only the page's, since
+// SSO flows set cookies on several domains
+func (d *cdpDriver) Cookies() ([]*http.Cookie, error) {
+	return cdpCookies(d.browser)
+}
+
+// Eval evaluates a JS function on the tab
+func (d *cdpDriver) Eval(js string) (string, error) {
+	result, err := d.page.Eval(js)
+	if err != nil {
+		return "", err
+	}
+	return result.Value.Str(), nil
+}
+
+// Popups returns the URLs of the open tabs and windows the opened tab started
+func (d *cdpDriver) Popups() ([]string, error) {
+	targets, err := proto.TargetGetTargets{}.Call(d.browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list targets: %w", err)
+	}
+	if d.popups == nil {
+		d.popups = map[proto.TargetTargetID]bool{}
+	}
+	return trackPopups(d.popups, d.page.TargetID, targets.TargetInfos), nil
+}
+
+// trackPopups adds the targets opened from root, or from a target already in
+// tracked, to tracked and returns the URLs of those that are open pages.
+// Tracked targets are kept after they close, so a popup opened from a popup
+// that has since gone is still followed.
+func trackPopups(tracked map[proto.TargetTargetID]bool, root proto.TargetTargetID, targets []*proto.TargetTargetInfo) []string {
+	// A popup may be listed before its opener, so repeat until nothing is added
+	for added := true; added; {
+		added = false
+		for _, t := range targets {
+			if t.TargetID == root || tracked[t.TargetID] || t.OpenerID == "" {
+				continue
+			}
+			if t.OpenerID == root || tracked[t.OpenerID] {
+				tracked[t.TargetID] = true
+				added = true
+			}
+		}
+	}
+
+	var urls []string
+	for _, t := range targets {
+		if tracked[t.TargetID] && t.Type == proto.TargetTargetInfoTypePage {
+			urls = append(urls, t.URL)
+		}
+	}
+	return urls
+}
+
+// OriginStorage returns the storage of an origin in the tab or frame that
+// has it loaded, preferring the driver's page
+func (d *cdpDriver) OriginStorage(origin string) (originStorage, error) {
+	frame, err := findOriginStorage(d.browser, d.page, origin)
+	if err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// credentialHeader reports whether a request header can carry a credential
+func credentialHeader(name string) bool {
+	name = strings.ToLower(name)
+	return name == "authorization" || strings.Contains(name, "token") || strings.HasSuffix(name, "api-key")
+}
+
+// WatchHeaders records the credential headers of the requests the tab
+// sends until the returned function is called, which returns the last
+// value of each
+func (d *cdpDriver) WatchHeaders() func() http.Header {
+	ctx, cancel := context.WithCancel(context.Background())
+	page := d.page.Context(ctx)
+	if err := (proto.NetworkEnable{}).Call(page); err != nil {
+		cancel()
+		return func() http.Header { return nil }
+	}
+
+	var mu sync.Mutex
+	headers := http.Header{}
+	wait := page.EachEvent(func(e *proto.NetworkRequestWillBeSent) {
+		mu.Lock()
+		defer mu.Unlock()
+		for name, value := range e.Request.Headers {
+			if credentialHeader(name) {
+				headers.Set(name, value.Str())
+			}
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	return func() http.Header {
+		cancel()
+		<-done
+		mu.Lock()
+		defer mu.Unlock()
+		return headers
+	}
+}
+
+// Close closes the tab, unless it was reused, and the browser too if this
+// driver owns it
+func (d *cdpDriver) Close() error {
+	var err error
+	if d.page != nil && !d.reused {
+		err = d.page.Close()
+	}
+	if d.closeBrowser {
+		err = d.browser.Close()
+	}
+	return err
+}
+
+// PageURLs lists the URLs of all tabs, for diagnosing captures from the wrong tab
+func (d *cdpDriver) PageURLs() ([]string, error) {
+	pages, err := d.browser.Pages()
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for _, p := range pages {
+		if info, err := p.Info(); err == nil {
+			urls = append(urls, info.URL)
+		}
+	}
+	return urls, nil
+}
+
+// rawCookie is a flexible struct for parsing CDP cookie responses
+// Chromium 136+ changed partitionKey from string to object, breaking Rod's types
+type rawCookie struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	Expires  float64 `json:"expires"`
+	Secure   bool    `json:"secure"`
+	HTTPOnly bool    `json:"httpOnly"`
+	SameSite string  `json:"sameSite"`
+}
+
+// cdpCookies extracts cookies from the browser and converts them to http.Cookie format
+func cdpCookies(browser *rod.Browser) ([]*http.Cookie, error) {
+	// Use raw CDP call to avoid Rod's outdated proto types
+	ctx := context.Background()
+	result, err := browser.Call(ctx, "", "Storage.getCookies", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cookies from browser: %w", err)
+	}
+
+	// Parse the raw JSON response
+	var response struct {
+		Cookies []rawCookie `json:"cookies"`
+	}
+	if err := json.Unmarshal(result, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse cookies response: %w", err)
+	}
+
+	var httpCookies []*http.Cookie
+	for _, c := range response.Cookies {
+		cookie := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Expires:  time.Unix(int64(c.Expires), 0),
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+			SameSite: parseSameSite(c.SameSite),
+		}
+		httpCookies = append(httpCookies, cookie)
+	}
+
+	return httpCookies, nil
+}
+
+// parseSameSite converts a SameSite attribute name to http.SameSite
+func parseSameSite(sameSite string) http.SameSite {
+	switch sameSite {
+	case "Strict":
+		return http.SameSiteStrictMode
+	case "Lax":
+		return http.SameSiteLaxMode
+	case "None":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
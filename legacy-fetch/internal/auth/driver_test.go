@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestTrackPopups(t *testing.T) {
+	page := func(id, opener, url string) *proto.TargetTargetInfo {
+		return &proto.TargetTargetInfo{
+			TargetID: proto.TargetTargetID(id),
+			OpenerID: proto.TargetTargetID(opener),
+			Type:     proto.TargetTargetInfoTypePage,
+			URL:      url,
+		}
+	}
+	tracked := map[proto.TargetTargetID]bool{}
+
+	// The consent popup is listed before the login popup that opened it
+	urls := trackPopups(tracked, "app", []*proto.TargetTargetInfo{
+		page("consent", "login", "https://login.microsoftonline.com/consent"),
+		page("app", "", "https://app.example.com/"),
+		page("other", "", "https://mail.example.com/"),
+		page("login", "app", "https://login.microsoftonline.com/authorize"),
+		page("unrelated", "other", "https://news.example.com/"),
+	})
+	if strings.Join(urls, ",") != "https://login.microsoftonline.com/consent,https://login.microsoftonline.com/authorize" {
+		t.Errorf("popups = %v", urls)
+	}
+
+	// The consent popup is still followed after the login popup closes
+	urls = trackPopups(tracked, "app", []*proto.TargetTargetInfo{
+		page("app", "", "https://app.example.com/"),
+		page("consent", "login", "https://app.example.com/callback"),
+	})
+	if len(urls) != 1 || urls[0] != "https://app.example.com/callback" {
+		t.Errorf("popups after opener closed = %v", urls)
+	}
+
+	if urls := trackPopups(tracked, "app", []*proto.TargetTargetInfo{page("app", "", "https://app.example.com/home")}); len(urls) != 0 {
+		t.Errorf("expected no popups once they close, got %v", urls)
+	}
+}
+
+func TestMatchTab(t *testing.T) {
+	tabs := []string{
+		"edge://newtab/",
+		"https://app.example.com/settings",
+		"",
+		"https://app.example.com/data-queue",
+		"http://app.example.com/data-queue",
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"https://app.example.com/data-queue", 3},
+		{"https://app.example.com/", 1},
+		{"http://app.example.com/", 4},
+		{"https://app.example.com:8443/", -1},
+		{"https://other.example.com/", -1},
+	}
+	for _, tt := range tests {
+		if got := matchTab(tabs, tt.target); got != tt.want {
+			t.Errorf("matchTab(%q) = %d, want %d", tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// firebaseUserPrefix starts the keys Firebase Auth persists its signed-in
+// user under: "firebase:authUser:<api key>:<app name>"
+const firebaseUserPrefix = "firebase:authUser:"
+
+// firebaseTokenURL is the securetoken endpoint that refreshes Firebase ID
+// tokens
+const firebaseTokenURL = "https://securetoken.googleapis.com/v1/token"
+
+// The JavaScript to read Firebase Auth's IndexedDB records as a JSON object
+// of fbase_key to JSON value. The database is not created if the app never
+// opened it.
+const firebaseIndexedDBJS = `() => new Promise((resolve) => {
+	const done = (records) => resolve(JSON.stringify(records));
+	if (!window.indexedDB) return done({});
+	const request = indexedDB.open("firebaseLocalStorageDb");
+	request.onupgradeneeded = () => request.transaction.abort();
+	request.onerror = () => done({});
+	request.onsuccess = () => {
+		const db = request.result;
+		if (!db.objectStoreNames.contains("firebaseLocalStorage")) {
+			db.close();
+			return done({});
+		}
+		const all = db.transaction("firebaseLocalStorage", "readonly").objectStore("firebaseLocalStorage").getAll();
+		all.onsuccess = () => {
+			const records = {};
+			for (const r of all.result) records[r.fbase_key] = JSON.stringify(r.value);
+			db.close();
+			done(records);
+		};
+		all.onerror = () => {
+			db.close();
+			done({});
+		};
+	};
+})`
+
+// readFirebaseIndexedDB reads the records of Firebase Auth's
+// firebaseLocalStorageDb from a driver's page
+func readFirebaseIndexedDB(driver BrowserDriver) (map[string]string, error) {
+	jsonStr, err := driver.Eval(firebaseIndexedDBJS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IndexedDB: %w", err)
+	}
+	return ParseLocalStorageJSON(jsonStr)
+}
+
+// firebaseUserEntry is the user object Firebase Auth persists
+type firebaseUserEntry struct {
+	UID             string `json:"uid"`
+	Email           string `json:"email"`
+	APIKey          string `json:"apiKey"`
+	AppName         string `json:"appName"`
+	StsTokenManager struct {
+		AccessToken    string  `json:"accessToken"`
+		RefreshToken   string  `json:"refreshToken"`
+		ExpirationTime float64 `json:"expirationTime"` // Unix milliseconds
+	} `json:"stsTokenManager"`
+}
+
+// FirebaseUser is a signed-in Firebase Auth user and its tokens
+type FirebaseUser struct {
+	APIKey       string    `json:"api_key"`
+	AppName      string    `json:"app_name"`
+	UID          string    `json:"uid"`
+	Email        string    `json:"email,omitempty"`
+	IDToken      string    `json:"id_token"` // What Firebase calls the access token
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	TokenURL     string    `json:"token_url,omitempty"` // securetoken endpoint; empty means Google's
+}
+
+// Expired reports whether the ID token expires within margin
+func (u *FirebaseUser) Expired(margin time.Duration) bool {
+	return !u.ExpiresAt.IsZero() && time.Now().Add(margin).After(u.ExpiresAt)
+}
+
+// CanRefresh reports whether the ID token can be refreshed without a browser
+func (u *FirebaseUser) CanRefresh() bool {
+	return u.RefreshToken != "" && u.APIKey != ""
+}
+
+// ProjectID returns the Firebase project the ID token was issued for
+func (u *FirebaseUser) ProjectID() string {
+	jwt, err := DecodeJWT(u.IDToken)
+	if err != nil {
+		return ""
+	}
+	return jwt.StringClaim("aud")
+}
+
+// ParseFirebaseUsers finds the Firebase Auth users persisted in storage:
+// the firebaseLocalStorageDb records, or localStorage and sessionStorage
+// for apps using those persistences. Entries that cannot be parsed are
+// skipped and reported as problems.
+func ParseFirebaseUsers(storage map[string]string) ([]*FirebaseUser, []error) {
+	var users []*FirebaseUser
+	var problems []error
+
+	for key, value := range storage {
+		if !strings.HasPrefix(key, firebaseUserPrefix) {
+			continue
+		}
+
+		var entry firebaseUserEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil {
+			problems = append(problems, fmt.Errorf("failed to parse Firebase user %q: %w", key, err))
+			continue
+		}
+		if entry.StsTokenManager.AccessToken == "" {
+			problems = append(problems, fmt.Errorf("Firebase user %q has no access token", key))
+			continue
+		}
+
+		user := &FirebaseUser{
+			APIKey:       entry.APIKey,
+			AppName:      entry.AppName,
+			UID:          entry.UID,
+			Email:        entry.Email,
+			IDToken:      entry.StsTokenManager.AccessToken,
+			RefreshToken: entry.StsTokenManager.RefreshToken,
+		}
+		// The key names the app too, for entries without the fields
+		if parts := strings.SplitN(strings.TrimPrefix(key, firebaseUserPrefix), ":", 2); len(parts) == 2 {
+			if user.APIKey == "" {
+				user.APIKey = parts[0]
+			}
+			if user.AppName == "" {
+				user.AppName = parts[1]
+			}
+		}
+		if entry.StsTokenManager.ExpirationTime > 0 {
+			user.ExpiresAt = time.UnixMilli(int64(entry.StsTokenManager.ExpirationTime))
+		}
+		users = append(users, user)
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].APIKey != users[j].APIKey {
+			return users[i].APIKey < users[j].APIKey
+		}
+		return users[i].AppName < users[j].AppName
+	})
+	return users, problems
+}
+
+// firebaseTokenResponse is the body of a securetoken refresh response
+type firebaseTokenResponse struct {
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    string `json:"expires_in"` // Seconds, as a string
+	UserID       string `json:"user_id"`
+	Error        struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// RefreshFirebaseUser exchanges the user's refresh token for a new ID token
+// with the securetoken refresh_token grant, as the Firebase SDK does
+func RefreshFirebaseUser(client *http.Client, user *FirebaseUser) (*FirebaseUser, error) {
+	if !user.CanRefresh() {
+		return nil, fmt.Errorf("no refresh token or API key to refresh with")
+	}
+
+	tokenURL := user.TokenURL
+	if tokenURL == "" {
+		tokenURL = firebaseTokenURL
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {user.RefreshToken},
+	}
+	resp, err := client.PostForm(tokenURL+"?key="+url.QueryEscape(user.APIKey), form)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach securetoken endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body firebaseTokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse securetoken response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if body.Error.Message != "" {
+			return nil, fmt.Errorf("token refresh rejected: %s", body.Error.Message)
+		}
+		return nil, fmt.Errorf("token refresh rejected: %s", resp.Status)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("securetoken response has no id_token")
+	}
+
+	refreshed := *user
+	refreshed.IDToken = body.IDToken
+	if body.RefreshToken != "" {
+		refreshed.RefreshToken = body.RefreshToken
+	}
+	refreshed.ExpiresAt = time.Time{}
+	if seconds, err := strconv.Atoi(body.ExpiresIn); err == nil && seconds > 0 {
+		refreshed.ExpiresAt = time.Now().Add(time.Duration(seconds) * time.Second)
+	}
+	return &refreshed, nil
+}
+
+// firebaseExtractor reads the Firebase Auth user from IndexedDB, or from
+// localStorage or sessionStorage
+type firebaseExtractor struct{}
+
+func (firebaseExtractor) Name() string { return "firebase" }
+
+// Extract returns the ID token of the user of the queried app: --audience
+// names its API key or Firebase project ID. A live token is preferred.
+func (e firebaseExtractor) Extract(result *AuthResult, query TokenQuery) (*ExtractedToken, error) {
+	all, problems := e.List(result)
+	if len(all) == 0 {
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("no usable Firebase user: %w", problems[0])
+		}
+		return nil, ErrNoToken
+	}
+
+	var expired *ExtractedToken
+	var apps []string
+	for _, token := range all {
+		user := token.Firebase
+		if query.Audience != "" && query.Audience != user.APIKey && query.Audience != token.Audience {
+			apps = append(apps, token.Audience)
+			continue
+		}
+		if !token.Expired(0) {
+			return token, nil
+		}
+		if expired == nil {
+			expired = token
+		}
+	}
+	if expired != nil {
+		return expired, nil
+	}
+	return nil, fmt.Errorf("no Firebase user for app %q (available projects: %s)", query.Audience, strings.Join(apps, ", "))
+}
+
+func (firebaseExtractor) List(result *AuthResult) ([]*ExtractedToken, []error) {
+	var all []*ExtractedToken
+	var problems []error
+	for _, storage := range []struct {
+		name    string
+		entries map[string]string
+	}{
+		{"IndexedDB firebaseLocalStorageDb", result.IndexedDB},
+		{"localStorage", result.LocalStorage},
+		{"sessionStorage", result.SessionStorage},
+	} {
+		users, errs := ParseFirebaseUsers(storage.entries)
+		problems = append(problems, errs...)
+		for _, user := range users {
+			all = append(all, &ExtractedToken{
+				Provider:  "firebase",
+				Token:     user.IDToken,
+				Source:    storage.name,
+				Audience:  user.ProjectID(),
+				Subject:   user.Email,
+				ExpiresAt: user.ExpiresAt,
+				Firebase:  user,
+			})
+		}
+	}
+	return all, problems
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFirebaseUsers(t *testing.T) {
+	idToken := testJWT(`{"aud":"partner-portal","exp":4102444800}`)
+	indexedDB := map[string]string{
+		"firebase:authUser:AIzaKey1:[DEFAULT]": `{"uid":"u1","email":"ada@example.com","apiKey":"AIzaKey1","appName":"[DEFAULT]",` +
+			`"stsTokenManager":{"refreshToken":"refresh-1","accessToken":"` + idToken + `","expirationTime":4102444800000}}`,
+		"firebase:authUser:AIzaKey2:[DEFAULT]":     `{not json`,
+		"firebase:redirectUser:AIzaKey1:[DEFAULT]": `{}`,
+	}
+
+	users, problems := ParseFirebaseUsers(indexedDB)
+	if len(users) != 1 || len(problems) != 1 {
+		t.Fatalf("expected one user and one problem, got %+v, %v", users, problems)
+	}
+	user := users[0]
+	if user.APIKey != "AIzaKey1" || user.Email != "ada@example.com" || user.IDToken != idToken || user.RefreshToken != "refresh-1" {
+		t.Errorf("unexpected user %+v", user)
+	}
+	if user.ExpiresAt.Year() != 2100 || user.ProjectID() != "partner-portal" {
+		t.Errorf("ExpiresAt = %v, ProjectID = %q", user.ExpiresAt, user.ProjectID())
+	}
+}
+
+func TestFirebaseExtractor(t *testing.T) {
+	live := testJWT(`{"aud":"partner-portal"}`)
+	expired := testJWT(`{"aud":"other-portal"}`)
+	result := &AuthResult{
+		IndexedDB: map[string]string{
+			"firebase:authUser:AIzaLive:[DEFAULT]": `{"apiKey":"AIzaLive","stsTokenManager":{"accessToken":"` + live + `","expirationTime":4102444800000}}`,
+		},
+		LocalStorage: map[string]string{
+			"firebase:authUser:AIzaOld:[DEFAULT]": `{"stsTokenManager":{"accessToken":"` + expired + `","refreshToken":"r","expirationTime":1700000000000}}`,
+		},
+	}
+
+	token, err := NewTokenExtractors().Extract(result, TokenQuery{}, "")
+	if err != nil || token.Provider != "firebase" || token.Token != live || token.Source != "IndexedDB firebaseLocalStorageDb" {
+		t.Fatalf("expected the live IndexedDB user, got %+v, %v", token, err)
+	}
+
+	token, err = firebaseExtractor{}.Extract(result, TokenQuery{Audience: "other-portal"})
+	if err != nil || token.Firebase.APIKey != "AIzaOld" || !token.Expired(0) {
+		t.Errorf("expected the expired user of the other project, with its API key from the key, got %+v, %v", token, err)
+	}
+	if _, err := (firebaseExtractor{}).Extract(result, TokenQuery{Audience: "unknown"}); err == nil {
+		t.Error("expected an error for an unknown app")
+	}
+}
+
+func TestRefreshFirebaseUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Query().Get("key") != "AIzaKey1" || r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{"code": 400, "message": "INVALID_REFRESH_TOKEN"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token":      "new-id-token",
+			"refresh_token": "refresh-2",
+			"expires_in":    "3600",
+			"token_type":    "Bearer",
+			"user_id":       "u1",
+		})
+	}))
+	defer server.Close()
+
+	user := &FirebaseUser{APIKey: "AIzaKey1", IDToken: "old", RefreshToken: "refresh-1", TokenURL: server.URL}
+	refreshed, err := RefreshFirebaseUser(server.Client(), user)
+	if err != nil {
+		t.Fatalf("RefreshFirebaseUser failed: %v", err)
+	}
+	if refreshed.IDToken != "new-id-token" || refreshed.RefreshToken != "refresh-2" || refreshed.Expired(time.Minute) {
+		t.Errorf("unexpected refreshed user %+v", refreshed)
+	}
+
+	user.RefreshToken = "revoked"
+	if _, err := RefreshFirebaseUser(server.Client(), user); err == nil || !strings.Contains(err.Error(), "INVALID_REFRESH_TOKEN") {
+		t.Errorf("expected the securetoken error, got %v", err)
+	}
+}
@@ -0,0 +1,390 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// jwksCacheTTL is how long a downloaded JWKS is trusted before it is
+// fetched again. A token signed with a key that is not in the cached set
+// refetches it sooner, since issuers rotate keys.
+const jwksCacheTTL = 24 * time.Hour
+
+// JWK is a public key in a JSON Web Key Set. Only the RSA and P-256 EC
+// members fetch verifies with are read.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg,omitempty"`
+	Use string `json:"use,omitempty"`
+	N   string `json:"n,omitempty"` // RSA modulus
+	E   string `json:"e,omitempty"` // RSA exponent
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"` // EC coordinates
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Key returns the signing key with the given kid. Keys whose use is not
+// "sig", such as encryption keys, are skipped. A token without a kid can
+// only be matched to a set holding a single signing key.
+func (s *JWKS) Key(kid string) (*JWK, bool) {
+	var signing []*JWK
+	for i := range s.Keys {
+		if s.Keys[i].Use == "" || s.Keys[i].Use == "sig" {
+			signing = append(signing, &s.Keys[i])
+		}
+	}
+
+	if kid == "" && len(signing) == 1 {
+		return signing[0], true
+	}
+	for _, key := range signing {
+		if key.Kid == kid {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// PublicKey decodes the key into an *rsa.PublicKey or *ecdsa.PublicKey
+func (k *JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus in key %q: %w", k.Kid, err)
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, fmt.Errorf("invalid RSA exponent in key %q", k.Kid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %q in key %q", k.Crv, k.Kid)
+		}
+		x, errX := decodeJWKInt(k.X)
+		y, errY := decodeJWKInt(k.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("invalid EC coordinates in key %q", k.Kid)
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("key %q is not on P-256", k.Kid)
+		}
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q in key %q", k.Kty, k.Kid)
+}
+
+// decodeJWKInt decodes a base64url big-endian integer
+func decodeJWKInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+// LoadJWKSFile reads a JWKS from a local file
+func LoadJWKSFile(path string) (*JWKS, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+	}
+	return &set, nil
+}
+
+// VerifySignature checks the token's signature with the matching key of
+// the set. Only RS256, PS256 and ES256 are accepted, so a token cannot
+// choose "none" or an HMAC algorithm keyed with a public key.
+func (j *JWT) VerifySignature(keys *JWKS) error {
+	alg, _ := j.Header["alg"].(string)
+	kid, _ := j.Header["kid"].(string)
+
+	jwk, ok := keys.Key(kid)
+	if !ok {
+		return fmt.Errorf("no key with kid %q in the JWKS", kid)
+	}
+	if jwk.Alg != "" && jwk.Alg != alg {
+		return fmt.Errorf("key %q is for %s, but the token is signed with %s", kid, jwk.Alg, alg)
+	}
+	publicKey, err := jwk.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	raw := strings.TrimSpace(j.Raw)
+	digest := sha256.Sum256([]byte(raw[:strings.LastIndex(raw, ".")]))
+
+	switch alg {
+	case "RS256", "PS256":
+		rsaKey, ok := publicKey.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s needs an RSA key, but key %q is %s", alg, kid, jwk.Kty)
+		}
+		if alg == "RS256" {
+			err = rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], j.Signature)
+		} else {
+			err = rsa.VerifyPSS(rsaKey, crypto.SHA256, digest[:], j.Signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		if err != nil {
+			return fmt.Errorf("invalid %s signature", alg)
+		}
+		return nil
+
+	case "ES256":
+		ecKey, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("ES256 needs an EC key, but key %q is %s", kid, jwk.Kty)
+		}
+		// JWS signatures are r and s concatenated, not ASN.1
+		if len(j.Signature) != 64 {
+			return fmt.Errorf("invalid ES256 signature length %d", len(j.Signature))
+		}
+		r := new(big.Int).SetBytes(j.Signature[:32])
+		s := new(big.Int).SetBytes(j.Signature[32:])
+		if !ecdsa.Verify(ecKey, digest[:], r, s) {
+			return fmt.Errorf("invalid ES256 signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported signing algorithm %q (supported: RS256, PS256, ES256)", alg)
+}
+
+// ClaimExpectations are the values a token's registered claims are checked
+// against. Empty fields are not checked; exp, which a token must have, and
+// nbf always are.
+type ClaimExpectations struct {
+	Issuer   string
+	Audience string
+	Leeway   time.Duration // Allowed clock skew for exp and nbf
+}
+
+// ValidateClaims checks iss, aud, exp and nbf at the given time
+func (j *JWT) ValidateClaims(expect ClaimExpectations, now time.Time) error {
+	if expect.Issuer != "" && strings.TrimSuffix(j.StringClaim("iss"), "/") != strings.TrimSuffix(expect.Issuer, "/") {
+		return fmt.Errorf("issuer is %q, expected %q", j.StringClaim("iss"), expect.Issuer)
+	}
+	if expect.Audience != "" {
+		found := false
+		for _, aud := range j.Audiences() {
+			if aud == expect.Audience {
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("audience %v does not include %q", j.Audiences(), expect.Audience)
+		}
+	}
+	exp, ok := j.TimeClaim("exp")
+	if !ok {
+		return fmt.Errorf("token has no exp claim")
+	}
+	if !now.Before(exp.Add(expect.Leeway)) {
+		return fmt.Errorf("token expired at %s", exp.Format(time.RFC3339))
+	}
+	if nbf, ok := j.TimeClaim("nbf"); ok && now.Add(expect.Leeway).Before(nbf) {
+		return fmt.Errorf("token is not valid before %s", nbf.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// JWKSCache downloads issuers' key sets through their OpenID configuration
+// and keeps them on disk
+type JWKSCache struct {
+	dir    string // Cache directory (e.g., ~/.omatic/jwks)
+	client *http.Client
+	ttl    time.Duration
+}
+
+// cachedJWKS is a key set as stored on disk
+type cachedJWKS struct {
+	Issuer    string    `json:"issuer"`
+	JWKSURI   string    `json:"jwks_uri"`
+	FetchedAt time.Time `json:"fetched_at"`
+	JWKS
+}
+
+// NewJWKSCache creates a JWKSCache in ~/.omatic/jwks
+func NewJWKSCache(client *http.Client) (*JWKSCache, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user home directory: %w", err)
+	}
+	return &JWKSCache{dir: filepath.Join(homeDir, ".omatic", "jwks"), client: client, ttl: jwksCacheTTL}, nil
+}
+
+// Keys returns the key set of an issuer, from the cache while it is fresh
+// unless refresh is set
+func (c *JWKSCache) Keys(issuer string, refresh bool) (*JWKS, error) {
+	if err := checkKeyURL("issuer", issuer); err != nil {
+		return nil, err
+	}
+	path := c.cachePath(issuer)
+	if !refresh {
+		if data, err := os.ReadFile(path); err == nil {
+			var cached cachedJWKS
+			if json.Unmarshal(data, &cached) == nil && time.Since(cached.FetchedAt) < c.ttl {
+				return &cached.JWKS, nil
+			}
+		}
+	}
+
+	jwksURI, err := c.discoverJWKSURI(issuer)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKeyURL("jwks_uri", jwksURI); err != nil {
+		return nil, err
+	}
+	var set JWKS
+	if err := c.getJSON(jwksURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+
+	data, err := json.MarshalIndent(cachedJWKS{Issuer: issuer, JWKSURI: jwksURI, FetchedAt: time.Now(), JWKS: set}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JWKS: %w", err)
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create JWKS cache directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write JWKS cache: %w", err)
+	}
+	return &set, nil
+}
+
+// discoverJWKSURI reads jwks_uri from the issuer's OpenID configuration,
+// which must name the same issuer
+func (c *JWKSCache) discoverJWKSURI(issuer string) (string, error) {
+	var config struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	configURL := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(configURL, &config); err != nil {
+		return "", fmt.Errorf("failed to discover the JWKS of %s: %w", issuer, err)
+	}
+	if strings.TrimSuffix(config.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return "", fmt.Errorf("OpenID configuration at %s is for issuer %q", configURL, config.Issuer)
+	}
+	if config.JWKSURI == "" {
+		return "", fmt.Errorf("OpenID configuration at %s has no jwks_uri", configURL)
+	}
+	return config.JWKSURI, nil
+}
+
+// checkKeyURL rejects URLs fetch will not download keys from: anything but
+// https, except http on a loopback address for a local issuer
+func checkKeyURL(name, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid %s URL %q", name, rawURL)
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		host := u.Hostname()
+		if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+			return nil
+		}
+		return fmt.Errorf("%s %s is not https; plain http is only accepted on loopback addresses", name, rawURL)
+	}
+	return fmt.Errorf("unsupported %s URL scheme %q", name, u.Scheme)
+}
+
+// getJSON fetches and decodes a JSON document
+func (c *JWKSCache) getJSON(url string, out interface{}) error {
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", url, err)
+	}
+	return nil
+}
+
+// cachePath returns the cache file for an issuer's key set
+func (c *JWKSCache) cachePath(issuer string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSuffix(issuer, "/")))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// VerifyOptions configure VerifyJWT
+type VerifyOptions struct {
+	ClaimExpectations
+	JWKSFile string // Verify with this local JWKS instead of discovering the issuer's
+}
+
+// VerifyJWT checks a token's signature and claims. The key set is the
+// JWKSFile, or else is discovered from the expected issuer through cache.
+// The token's own iss is never used to find keys, since anyone can sign a
+// token naming an issuer they host. A kid missing from a cached set
+// refetches it once, in case the issuer rotated its keys.
+func VerifyJWT(jwt *JWT, opts VerifyOptions, cache *JWKSCache, now time.Time) error {
+	// Claims first, so a token from the wrong issuer never triggers a download
+	if err := jwt.ValidateClaims(opts.ClaimExpectations, now); err != nil {
+		return err
+	}
+
+	if opts.JWKSFile != "" {
+		keys, err := LoadJWKSFile(opts.JWKSFile)
+		if err != nil {
+			return err
+		}
+		return jwt.VerifySignature(keys)
+	}
+
+	issuer := opts.Issuer
+	if issuer == "" {
+		return fmt.Errorf("no issuer to trust: give the expected issuer or a JWKS file to verify with")
+	}
+
+	keys, err := cache.Keys(issuer, false)
+	if err != nil {
+		return err
+	}
+	kid, _ := jwt.Header["kid"].(string)
+	if _, ok := keys.Key(kid); !ok {
+		if keys, err = cache.Keys(issuer, true); err != nil {
+			return err
+		}
+	}
+	return jwt.VerifySignature(keys)
+}
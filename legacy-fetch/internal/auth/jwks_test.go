@@ -0,0 +1,251 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIssuer is a local stand-in for an OpenID provider with one RSA and
+// one P-256 key
+type testIssuer struct {
+	server    *httptest.Server
+	rsaKey    *rsa.PrivateKey
+	ecKey     *ecdsa.PrivateKey
+	jwksCalls int
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &testIssuer{rsaKey: rsaKey, ecKey: ecKey}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": issuer.server.URL + "/", "jwks_uri": issuer.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.jwksCalls++
+		json.NewEncoder(w).Encode(issuer.jwks())
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) jwks() *JWKS {
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	return &JWKS{Keys: []JWK{
+		{Kty: "RSA", Kid: "rsa1", N: b64(i.rsaKey.N.Bytes()), E: b64(big.NewInt(int64(i.rsaKey.E)).Bytes())},
+		{Kty: "EC", Kid: "ec1", Crv: "P-256", X: b64(i.ecKey.X.FillBytes(make([]byte, 32))), Y: b64(i.ecKey.Y.FillBytes(make([]byte, 32)))},
+	}}
+}
+
+// sign issues a token with the given algorithm, kid and claims
+func (i *testIssuer) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	b64 := base64.RawURLEncoding.EncodeToString
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, i.rsaKey, crypto.SHA256, digest[:])
+	case "PS256":
+		signature, err = rsa.SignPSS(rand.Reader, i.rsaKey, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, i.ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + b64(signature)
+}
+
+func (i *testIssuer) claims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss": i.server.URL + "/",
+		"aud": []string{"https://api.example.com"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"nbf": time.Now().Add(-time.Minute).Unix(),
+	}
+}
+
+func TestVerifyJWT_Algorithms(t *testing.T) {
+	issuer := newTestIssuer(t)
+	cache := &JWKSCache{dir: t.TempDir(), client: issuer.server.Client(), ttl: time.Hour}
+	opts := VerifyOptions{ClaimExpectations: ClaimExpectations{Issuer: issuer.server.URL, Audience: "https://api.example.com"}}
+
+	for _, tc := range []struct{ alg, kid string }{{"RS256", "rsa1"}, {"PS256", "rsa1"}, {"ES256", "ec1"}} {
+		t.Run(tc.alg, func(t *testing.T) {
+			jwt, err := DecodeJWT(issuer.sign(t, tc.alg, tc.kid, issuer.claims()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := VerifyJWT(jwt, opts, cache, time.Now()); err != nil {
+				t.Errorf("VerifyJWT failed: %v", err)
+			}
+
+			// Tampering with the claims breaks the signature
+			parts := strings.Split(jwt.Raw, ".")
+			claims := issuer.claims()
+			claims["sub"] = "admin"
+			payload, _ := json.Marshal(claims)
+			tampered, _ := DecodeJWT(parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2])
+			if err := VerifyJWT(tampered, opts, cache, time.Now()); err == nil || !strings.Contains(err.Error(), "invalid") {
+				t.Errorf("expected a tampered token to fail, got %v", err)
+			}
+		})
+	}
+
+	if issuer.jwksCalls != 1 {
+		t.Errorf("expected the JWKS to be fetched once and then cached, got %d fetches", issuer.jwksCalls)
+	}
+}
+
+func TestVerifyJWT_RejectsOtherAlgorithms(t *testing.T) {
+	issuer := newTestIssuer(t)
+	set := issuer.jwks()
+	for _, token := range []string{
+		testJWT(`{}`), // RS256 header with a bogus signature
+		strings.Replace(testJWT(`{}`), strings.Split(testJWT(`{}`), ".")[0], base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa1"}`)), 1),
+		strings.Replace(testJWT(`{}`), strings.Split(testJWT(`{}`), ".")[0], base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","kid":"rsa1"}`)), 1),
+	} {
+		jwt, err := DecodeJWT(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := jwt.VerifySignature(set); err == nil {
+			t.Errorf("expected %v to be rejected", jwt.Header)
+		}
+	}
+}
+
+func TestVerifyJWT_Claims(t *testing.T) {
+	issuer := newTestIssuer(t)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	data, _ := json.Marshal(issuer.jwks())
+	os.WriteFile(file, data, 0600)
+
+	now := time.Now()
+	tests := []struct {
+		name    string
+		change  map[string]interface{}
+		expect  ClaimExpectations
+		wantErr string
+	}{
+		{"valid", nil, ClaimExpectations{Issuer: issuer.server.URL + "/", Audience: "https://api.example.com"}, ""},
+		{"wrong issuer", nil, ClaimExpectations{Issuer: "https://evil.example.com"}, "issuer"},
+		{"wrong audience", nil, ClaimExpectations{Audience: "https://other.example.com"}, "audience"},
+		{"expired", map[string]interface{}{"exp": now.Add(-time.Hour).Unix()}, ClaimExpectations{}, "expired"},
+		{"expired within leeway", map[string]interface{}{"exp": now.Add(-time.Second).Unix()}, ClaimExpectations{Leeway: time.Minute}, ""},
+		{"not yet valid", map[string]interface{}{"nbf": now.Add(time.Hour).Unix()}, ClaimExpectations{}, "not valid before"},
+		{"no exp", map[string]interface{}{"exp": nil}, ClaimExpectations{}, "no exp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := issuer.claims()
+			for k, v := range tt.change {
+				if v == nil {
+					delete(claims, k)
+					continue
+				}
+				claims[k] = v
+			}
+			jwt, _ := DecodeJWT(issuer.sign(t, "RS256", "rsa1", claims))
+
+			// A local JWKS file needs no issuer stand-in
+			err := VerifyJWT(jwt, VerifyOptions{ClaimExpectations: tt.expect, JWKSFile: file}, nil, now)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestJWKSCache_RefetchesUnknownKid(t *testing.T) {
+	issuer := newTestIssuer(t)
+	dir := t.TempDir()
+	cache := &JWKSCache{dir: dir, client: issuer.server.Client(), ttl: time.Hour}
+
+	// A stale cached set from before the issuer rotated in ec1
+	old := cachedJWKS{Issuer: issuer.server.URL, FetchedAt: time.Now(), JWKS: JWKS{Keys: issuer.jwks().Keys[:1]}}
+	data, _ := json.Marshal(old)
+	os.WriteFile(cache.cachePath(issuer.server.URL), data, 0600)
+
+	jwt, _ := DecodeJWT(issuer.sign(t, "ES256", "ec1", issuer.claims()))
+	opts := VerifyOptions{ClaimExpectations: ClaimExpectations{Issuer: issuer.server.URL}}
+	if err := VerifyJWT(jwt, opts, cache, time.Now()); err != nil {
+		t.Fatalf("VerifyJWT failed: %v", err)
+	}
+	if issuer.jwksCalls != 1 {
+		t.Errorf("expected one refetch for the unknown kid, got %d", issuer.jwksCalls)
+	}
+}
+
+func TestVerifyJWT_NeedsTrustedIssuer(t *testing.T) {
+	issuer := newTestIssuer(t)
+	cache := &JWKSCache{dir: t.TempDir(), client: issuer.server.Client(), ttl: time.Hour}
+	jwt, _ := DecodeJWT(issuer.sign(t, "RS256", "rsa1", issuer.claims()))
+
+	// The token names its issuer, but that is not a reason to trust it
+	if err := VerifyJWT(jwt, VerifyOptions{}, cache, time.Now()); err == nil || !strings.Contains(err.Error(), "no issuer to trust") {
+		t.Errorf("expected verification without an issuer or JWKS to be refused, got %v", err)
+	}
+	if issuer.jwksCalls != 0 {
+		t.Errorf("expected no key download, got %d", issuer.jwksCalls)
+	}
+
+	for _, bad := range []string{"http://10.0.0.5/", "http://metadata.internal", "file:///etc/jwks.json"} {
+		if _, err := cache.Keys(bad, false); err == nil {
+			t.Errorf("expected issuer %s to be rejected", bad)
+		}
+	}
+	for _, ok := range []string{"https://tenant.auth0.com/", "http://localhost:8080", "http://127.0.0.1:9000", "http://[::1]:9000"} {
+		if err := checkKeyURL("issuer", ok); err != nil {
+			t.Errorf("expected issuer %s to be accepted, got %v", ok, err)
+		}
+	}
+}
+
+func TestJWKS_KeySkipsNonSigningKeys(t *testing.T) {
+	set := &JWKS{Keys: []JWK{
+		{Kty: "RSA", Kid: "enc1", Use: "enc"},
+		{Kty: "RSA", Kid: "sig1", Use: "sig"},
+	}}
+	if _, ok := set.Key("enc1"); ok {
+		t.Error("expected the encryption key to be skipped")
+	}
+	if key, ok := set.Key(""); !ok || key.Kid != "sig1" {
+		t.Errorf("expected the only signing key for a token without kid, got %+v", key)
+	}
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// JWT is a decoded JSON Web Token. Decoding does not verify the signature.
+type JWT struct {
+	Raw       string
+	Header    map[string]interface{}
+	Claims    map[string]interface{}
+	Signature []byte
+}
+
+// DecodeJWT splits a compact JWT and decodes its header and claims
+func DecodeJWT(token string) (*JWT, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("not a JWT: expected 3 dot-separated parts, got %d", len(parts))
+	}
+
+	jwt := &JWT{Raw: token}
+	if err := decodeJWTPart(parts[0], &jwt.Header); err != nil {
+		return nil, fmt.Errorf("failed to decode JWT header: %w", err)
+	}
+	if err := decodeJWTPart(parts[1], &jwt.Claims); err != nil {
+		return nil, fmt.Errorf("failed to decode JWT claims: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode JWT signature: %w", err)
+	}
+	jwt.Signature = signature
+	return jwt, nil
+}
+
+// decodeJWTPart decodes one base64url JSON segment of a JWT
+func decodeJWTPart(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// StringClaim returns a string claim, or "" if it is missing or not a string
+func (j *JWT) StringClaim(name string) string {
+	value, _ := j.Claims[name].(string)
+	return value
+}
+
+// TimeClaim returns a NumericDate claim such as exp or iat
+func (j *JWT) TimeClaim(name string) (time.Time, bool) {
+	seconds, ok := j.Claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// StringsClaim returns a claim that holds a string array, or a single
+// string, which is split on spaces as OAuth scope claims are
+func (j *JWT) StringsClaim(name string) []string {
+	switch value := j.Claims[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		var values []string
+		for _, v := range value {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// Audiences returns the aud claim, which may be a string or an array
+func (j *JWT) Audiences() []string {
+	if aud := j.StringClaim("aud"); aud != "" {
+		return []string{aud}
+	}
+	return j.StringsClaim("aud")
+}
+
+// Scopes returns the granted scopes: scope (OAuth, Auth0, Cognito) or scp
+// (Azure AD)
+func (j *JWT) Scopes() []string {
+	if scopes := j.StringsClaim("scope"); len(scopes) > 0 {
+		return scopes
+	}
+	return j.StringsClaim("scp")
+}
+
+// Roles returns the roles claim (Azure AD app roles, Keycloak-style) or the
+// Cognito groups
+func (j *JWT) Roles() []string {
+	if roles := j.StringsClaim("roles"); len(roles) > 0 {
+		return roles
+	}
+	return j.StringsClaim("cognito:groups")
+}
+
+// Tenant returns the tenant or organization the token was issued in: tid
+// (Azure AD), tenant_id or org_id (Auth0 Organizations)
+func (j *JWT) Tenant() string {
+	for _, name := range []string{"tid", "tenant_id", "org_id"} {
+		if tenant := j.StringClaim(name); tenant != "" {
+			return tenant
+		}
+	}
+	return ""
+}
+
+// TrimJWT strips what a token is commonly pasted with: surrounding space, a
+// "JWT=" line from fetch token, an "Authorization:" header name and the
+// "Bearer" scheme
+func TrimJWT(input string) string {
+	token := strings.TrimSpace(input)
+	token = strings.TrimPrefix(token, "JWT=")
+	if name, value, ok := strings.Cut(token, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "authorization") {
+		token = strings.TrimSpace(value)
+	}
+	if scheme, value, ok := strings.Cut(token, " "); ok && strings.EqualFold(scheme, "bearer") {
+		token = strings.TrimSpace(value)
+	}
+	return token
+}
+
+// JWTSummary is what fetch jwt decode reports about a token
+type JWTSummary struct {
+	Header    map[string]interface{} `json:"header"`
+	Claims    map[string]interface{} `json:"claims"`
+	Issuer    string                 `json:"issuer,omitempty"`
+	Subject   string                 `json:"subject,omitempty"`
+	Audiences []string               `json:"audiences,omitempty"`
+	Scopes    []string               `json:"scopes,omitempty"`
+	Roles     []string               `json:"roles,omitempty"`
+	Tenant    string                 `json:"tenant,omitempty"`
+	IssuedAt  *time.Time             `json:"issued_at,omitempty"`
+	ExpiresAt *time.Time             `json:"expires_at,omitempty"`
+	ExpiresIn int64                  `json:"expires_in_seconds,omitempty"` // Negative once expired
+	Expired   bool                   `json:"expired"`
+}
+
+// Summarize collects the token's commonly needed claims, with its expiry
+// relative to now
+func (j *JWT) Summarize(now time.Time) *JWTSummary {
+	summary := &JWTSummary{
+		Header:    j.Header,
+		Claims:    j.Claims,
+		Issuer:    j.StringClaim("iss"),
+		Subject:   j.StringClaim("sub"),
+		Audiences: j.Audiences(),
+		Scopes:    j.Scopes(),
+		Roles:     j.Roles(),
+		Tenant:    j.Tenant(),
+	}
+	if iat, ok := j.TimeClaim("iat"); ok {
+		summary.IssuedAt = &iat
+	}
+	if exp, ok := j.TimeClaim("exp"); ok {
+		summary.ExpiresAt = &exp
+		summary.ExpiresIn = int64(exp.Sub(now).Seconds())
+		summary.Expired = !now.Before(exp)
+	}
+	return summary
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testJWT builds an unsigned JWT with the given claims JSON
+func testJWT(claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256","typ":"JWT","kid":"k1"}`)) + "." + encode([]byte(claims)) + "." + encode([]byte("sig"))
+}
+
+func TestDecodeJWT(t *testing.T) {
+	jwt, err := DecodeJWT(testJWT(`{"iss":"https://tenant.auth0.com/","exp":1893456000,"aud":["a","b"]}`))
+	if err != nil {
+		t.Fatalf("DecodeJWT failed: %v", err)
+	}
+	if jwt.Header["kid"] != "k1" || string(jwt.Signature) != "sig" {
+		t.Errorf("unexpected header %v / signature %q", jwt.Header, jwt.Signature)
+	}
+	if iss := jwt.StringClaim("iss"); iss != "https://tenant.auth0.com/" {
+		t.Errorf("iss = %q", iss)
+	}
+	if exp, ok := jwt.TimeClaim("exp"); !ok || exp.Unix() != 1893456000 {
+		t.Errorf("exp = %v, %v", exp, ok)
+	}
+	if jwt.StringClaim("aud") != "" {
+		t.Error("expected a non-string claim to read as empty")
+	}
+}
+
+func TestDecodeJWT_Invalid(t *testing.T) {
+	for _, token := range []string{"opaque-token", "a.b", "!!.e30.c2ln", testJWT(`{"iss":`)} {
+		if _, err := DecodeJWT(token); err == nil {
+			t.Errorf("expected DecodeJWT(%q) to fail", token)
+		}
+	}
+	if _, err := DecodeJWT("a.b"); err == nil || !strings.Contains(err.Error(), "3 dot-separated") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestJWT_Summarize(t *testing.T) {
+	jwt, err := DecodeJWT(testJWT(`{"iss":"https://login.microsoftonline.com/t1/v2.0","sub":"u1","aud":"api://orders",` +
+		`"scp":"Orders.Read Orders.Write","roles":["Admin"],"tid":"t1","iat":1700000000,"exp":1700003600}`))
+	if err != nil {
+		t.Fatalf("DecodeJWT failed: %v", err)
+	}
+
+	summary := jwt.Summarize(time.Unix(1700000600, 0))
+	if summary.Issuer != "https://login.microsoftonline.com/t1/v2.0" || summary.Subject != "u1" || summary.Tenant != "t1" {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if strings.Join(summary.Audiences, ",") != "api://orders" || strings.Join(summary.Scopes, ",") != "Orders.Read,Orders.Write" ||
+		strings.Join(summary.Roles, ",") != "Admin" {
+		t.Errorf("audiences %v, scopes %v, roles %v", summary.Audiences, summary.Scopes, summary.Roles)
+	}
+	if summary.ExpiresIn != 3000 || summary.Expired {
+		t.Errorf("ExpiresIn = %d, Expired = %v", summary.ExpiresIn, summary.Expired)
+	}
+
+	if summary := jwt.Summarize(time.Unix(1700003600, 0)); !summary.Expired {
+		t.Error("expected the token to be expired at exp")
+	}
+}
+
+func TestJWT_CognitoGroupsAndScope(t *testing.T) {
+	jwt, _ := DecodeJWT(testJWT(`{"scope":"aws.cognito.signin.user.admin","cognito:groups":["ops"]}`))
+	if strings.Join(jwt.Scopes(), ",") != "aws.cognito.signin.user.admin" || strings.Join(jwt.Roles(), ",") != "ops" {
+		t.Errorf("scopes %v, roles %v", jwt.Scopes(), jwt.Roles())
+	}
+	if summary := jwt.Summarize(time.Now()); summary.ExpiresAt != nil || summary.Expired {
+		t.Error("expected a token without exp not to expire")
+	}
+}
+
+func TestTrimJWT(t *testing.T) {
+	for _, input := range []string{
+		"abc.def.ghi",
+		"  abc.def.ghi\n",
+		"JWT=abc.def.ghi",
+		"Bearer abc.def.ghi",
+		"Authorization: Bearer abc.def.ghi",
+		"authorization:bearer abc.def.ghi",
+	} {
+		if got := TrimJWT(input); got != "abc.def.ghi" {
+			t.Errorf("TrimJWT(%q) = %q", input, got)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Launcher is an OS-level shortcut that starts a browser with its debug port
+// open and its debug profile, so the browser the user opens every day is one
+// fetch can attach to
+type Launcher struct {
+	Browser BrowserType
+	Path    string   // Where the launcher is written
+	Exec    string   // Browser executable
+	Args    []string // Browser arguments
+	Content []byte   // File content; nil for Windows shortcuts, which are made through PowerShell
+	Mode    os.FileMode
+
+	shortcutPath string // Windows form of Path, for PowerShell
+	exePath      string // Windows form of Exec, for the shortcut target
+}
+
+// CommandLine returns the launcher's command for display
+func (l *Launcher) CommandLine() string {
+	parts := []string{shellQuote(l.Exec)}
+	for _, arg := range l.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// PlanLauncher describes the launcher fetch setup writes for a browser on
+// this machine
+func PlanLauncher(config *BrowserConfig) (*Launcher, error) {
+	return hostDiscoveryEnv().planLauncher(config)
+}
+
+// planLauncher builds the platform's launcher for a browser: a .desktop entry
+// on Linux, a .command script on macOS and a Start Menu shortcut on Windows
+// (also from WSL)
+func (e discoveryEnv) planLauncher(config *BrowserConfig) (*Launcher, error) {
+	if config.Protocol != ProtocolCDP {
+		return nil, fmt.Errorf("%s is driven over WebDriver and needs no launcher", config.Type)
+	}
+
+	name := config.DisplayName
+	if name == "" {
+		name = string(config.Type)
+	}
+
+	l := &Launcher{
+		Browser: config.Type,
+		Exec:    config.ExePath,
+		Args:    config.portLaunchArgs(),
+		Mode:    0644,
+	}
+
+	switch e.goos {
+	case "windows", "wsl":
+		appData := e.getenv("APPDATA")
+		if appData == "" {
+			return nil, fmt.Errorf("APPDATA is not set; cannot locate the Start Menu")
+		}
+		l.shortcutPath = windowsStylePath(path.Join(appData, "Microsoft/Windows/Start Menu/Programs", name+" (Debug).lnk"))
+		l.exePath = windowsStylePath(config.ExePath)
+		l.Path = l.shortcutPath
+		if e.goos == "wsl" {
+			l.exePath = windowsFromMountPath(config.ExePath)
+			l.Path = wslMountPath(l.shortcutPath)
+		}
+
+	case "darwin":
+		l.Path = path.Join(e.homeDir, "Applications", name+" (Debug).command")
+		l.Mode = 0755
+		l.Content = []byte(fmt.Sprintf("#!/bin/sh\n# %s with remote debugging on port %d for fetch\nexec %s \"$@\"\n",
+			name, config.DebugPort, l.CommandLine()))
+
+	default:
+		dataHome := e.getenv("XDG_DATA_HOME")
+		if dataHome == "" {
+			dataHome = path.Join(e.homeDir, ".local", "share")
+		}
+		l.Path = path.Join(dataHome, "applications", fmt.Sprintf("fetch-%s-debug.desktop", config.Type))
+		l.Content = []byte(desktopEntry(name, config, l))
+	}
+
+	return l, nil
+}
+
+// desktopEntry renders a freedesktop.org .desktop file for a launcher
+func desktopEntry(name string, config *BrowserConfig, l *Launcher) string {
+	execParts := []string{desktopQuote(l.Exec)}
+	for _, arg := range l.Args {
+		execParts = append(execParts, desktopQuote(arg))
+	}
+	execParts = append(execParts, "%U")
+
+	var b strings.Builder
+	b.WriteString("[Desktop Entry]\n")
+	b.WriteString("Type=Application\n")
+	fmt.Fprintf(&b, "Name=%s (Debug)\n", name)
+	fmt.Fprintf(&b, "Comment=%s with remote debugging on port %d for fetch\n", name, config.DebugPort)
+	fmt.Fprintf(&b, "Exec=%s\n", strings.Join(execParts, " "))
+	fmt.Fprintf(&b, "Icon=%s\n", strings.TrimSuffix(filepath.Base(config.ExePath), "-stable"))
+	b.WriteString("Categories=Network;WebBrowser;\n")
+	b.WriteString("MimeType=text/html;x-scheme-handler/http;x-scheme-handler/https;\n")
+	b.WriteString("Terminal=false\n")
+	return b.String()
+}
+
+// desktopQuote quotes an Exec argument as the Desktop Entry spec requires:
+// reserved characters force double quotes, inside which " ` $ \ are
+// backslash-escaped, and a literal % is doubled
+func desktopQuote(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", "$", `\$`).Replace(arg)
+	return `"` + escaped + `"`
+}
+
+// shellQuote single-quotes an argument for a POSIX shell when needed
+func shellQuote(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`[]{}") {
+		return arg
+	}
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
+
+// windowsFromMountPath converts a /mnt/c/x path back to C:\x
+func windowsFromMountPath(p string) string {
+	if len(p) < 6 || !strings.HasPrefix(p, "/mnt/") || (len(p) > 6 && p[6] != '/') {
+		return p
+	}
+	return strings.ToUpper(p[5:6]) + ":" + windowsStylePath(p[6:])
+}
+
+// windowsArgs joins arguments into a Windows command line
+func windowsArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if strings.ContainsAny(arg, " \t") {
+			// --flag=value with spaces: quote the value as Chromium expects
+			if eq := strings.Index(arg, "="); eq > 0 {
+				arg = arg[:eq+1] + `"` + arg[eq+1:] + `"`
+			} else {
+				arg = `"` + arg + `"`
+			}
+		}
+		quoted[i] = arg
+	}
+	return strings.Join(quoted, " ")
+}
+
+// WriteLauncher installs a launcher. An existing launcher at the same path
+// is backed up once to <path>.bak first.
+func WriteLauncher(l *Launcher) error {
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create launcher directory: %w", err)
+	}
+
+	if _, err := os.Stat(l.Path); err == nil {
+		backup := l.Path + ".bak"
+		if _, err := os.Stat(backup); os.IsNotExist(err) {
+			if _, err := copyFile(l.Path, backup); err != nil {
+				return fmt.Errorf("failed to back up existing launcher: %w", err)
+			}
+		}
+	}
+
+	if l.Content != nil {
+		if err := os.WriteFile(l.Path, l.Content, l.Mode); err != nil {
+			return fmt.Errorf("failed to write launcher: %w", err)
+		}
+		return os.Chmod(l.Path, l.Mode)
+	}
+	return writeWindowsShortcut(l)
+}
+
+// writeWindowsShortcut creates a .lnk through PowerShell's WScript.Shell,
+// as the old setup scripts did
+func writeWindowsShortcut(l *Launcher) error {
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+	script := strings.Join([]string{
+		"$s = (New-Object -ComObject WScript.Shell).CreateShortcut(" + quote(l.shortcutPath) + ")",
+		"$s.TargetPath = " + quote(l.exePath),
+		"$s.Arguments = " + quote(windowsArgs(l.Args)),
+		"$s.IconLocation = " + quote(l.exePath+",0"),
+		"$s.Description = " + quote(fmt.Sprintf("%s with remote debugging for fetch", l.Browser)),
+		"$s.Save()",
+	}, "; ")
+
+	shell := "powershell"
+	if IsWSL() {
+		shell = "powershell.exe"
+	}
+	cmd := exec.Command(shell, "-NoProfile", "-NonInteractive", "-Command", script)
+	if IsWSL() {
+		cmd.Dir = "/mnt/c"
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to create shortcut: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// ValidateSetup checks the launch configuration of the browsers fetch setup
+// is about to write launchers for. Each problem names its browser.
+func ValidateSetup(configs []*BrowserConfig) []string {
+	var problems []string
+	ports := map[int]BrowserType{}
+
+	for _, c := range configs {
+		if !c.ExeFound {
+			problems = append(problems, fmt.Sprintf("%s: not installed (%s)", c.Type, c.ExeReason))
+		}
+
+		if c.SourceUserDataDir != "" && c.UserDataDir == c.SourceUserDataDir {
+			problems = append(problems, fmt.Sprintf(
+				"%s: user-data dir %s is the browser's default, where Chromium 136+ ignores --remote-debugging-port",
+				c.Type, c.UserDataDir))
+		}
+
+		if other, dup := ports[c.DebugPort]; dup {
+			problems = append(problems, fmt.Sprintf("%s: debug port %d is also used by %s", c.Type, c.DebugPort, other))
+		}
+		ports[c.DebugPort] = c.Type
+
+		version, err := c.DebugVersion()
+		if err == nil {
+			err = version.Matches(c.Type)
+		}
+		if err != nil && err != errNoDebugListener {
+			problems = append(problems, fmt.Sprintf("%s: port %d: %v", c.Type, c.DebugPort, err))
+		}
+	}
+	return problems
+}
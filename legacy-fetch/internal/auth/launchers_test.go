@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlanLauncher_LinuxDesktopEntry(t *testing.T) {
+	env := fakeDiscoveryEnv("linux", map[string]string{"microsoft-edge-stable": "/usr/bin/microsoft-edge-stable"})
+	config := env.browserConfig(builtinDefinition(t, BrowserEdge))
+	config.ProfileDirectory = "Profile 2"
+
+	l, err := env.planLauncher(config)
+	if err != nil {
+		t.Fatalf("planLauncher failed: %v", err)
+	}
+	if l.Path != "/home/dev/.local/share/applications/fetch-edge-debug.desktop" {
+		t.Errorf("Path = %q", l.Path)
+	}
+
+	content := string(l.Content)
+	for _, want := range []string{
+		"[Desktop Entry]\n",
+		"Name=Microsoft Edge (Debug)\n",
+		`Exec=/usr/bin/microsoft-edge-stable --remote-debugging-port=9222 --user-data-dir=/home/dev/.config/microsoft-edge-debug "--profile-directory=Profile 2" %U` + "\n",
+		"Icon=microsoft-edge\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("desktop entry missing %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestPlanLauncher_Platforms(t *testing.T) {
+	tests := []struct {
+		goos     string
+		exe      string
+		wantPath string
+		wantExe  string
+	}{
+		{"darwin", "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", "/home/dev/Applications/Google Chrome (Debug).command", ""},
+		{"windows", "C:/Program Files/Google/Chrome/Application/chrome.exe",
+			`C:\Users\dev\AppData\Roaming\Microsoft\Windows\Start Menu\Programs\Google Chrome (Debug).lnk`,
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`},
+		{"wsl", "/mnt/c/Program Files/Google/Chrome/Application/chrome.exe",
+			"/mnt/c/Users/dev/AppData/Roaming/Microsoft/Windows/Start Menu/Programs/Google Chrome (Debug).lnk",
+			`C:\Program Files\Google\Chrome\Application\chrome.exe`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.goos, func(t *testing.T) {
+			env := fakeDiscoveryEnv(tt.goos, nil, tt.exe)
+			l, err := env.planLauncher(env.browserConfig(builtinDefinition(t, BrowserChrome)))
+			if err != nil {
+				t.Fatalf("planLauncher failed: %v", err)
+			}
+			if l.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q", l.Path, tt.wantPath)
+			}
+			if tt.wantExe != "" && l.exePath != tt.wantExe {
+				t.Errorf("shortcut target = %q, want %q", l.exePath, tt.wantExe)
+			}
+			if tt.goos == "darwin" && (l.Mode != 0755 || !strings.Contains(string(l.Content), "exec '/Applications/Google Chrome.app/")) {
+				t.Errorf("unexpected script (mode %v):\n%s", l.Mode, l.Content)
+			}
+		})
+	}
+}
+
+func TestDesktopQuote(t *testing.T) {
+	tests := map[string]string{
+		"--remote-debugging-port=9222": "--remote-debugging-port=9222",
+		"/opt/my browser/chrome":       `"/opt/my browser/chrome"`,
+		`--user-data-dir=$HOME`:        `"--user-data-dir=\$HOME"`,
+		"100%":                         "100%%",
+	}
+	for in, want := range tests {
+		if got := desktopQuote(in); got != want {
+			t.Errorf("desktopQuote(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWindowsArgs(t *testing.T) {
+	got := windowsArgs([]string{"--remote-debugging-port=9222", `--user-data-dir=C:\Users\Dev User\EdgeDebug`})
+	want := `--remote-debugging-port=9222 --user-data-dir="C:\Users\Dev User\EdgeDebug"`
+	if got != want {
+		t.Errorf("windowsArgs = %q, want %q", got, want)
+	}
+}
+
+func TestWindowsFromMountPath(t *testing.T) {
+	tests := map[string]string{
+		"/mnt/c/Program Files/Edge/msedge.exe": `C:\Program Files\Edge\msedge.exe`,
+		"/mnt/wsl/file":                        "/mnt/wsl/file",
+		"/usr/bin/chrome":                      "/usr/bin/chrome",
+	}
+	for in, want := range tests {
+		if got := windowsFromMountPath(in); got != want {
+			t.Errorf("windowsFromMountPath(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidateSetup(t *testing.T) {
+	configs := []*BrowserConfig{
+		{Type: BrowserEdge, ExeReason: "found on PATH", ExeFound: true, DebugPort: 1, UserDataDir: "/a", SourceUserDataDir: "/a"},
+		{Type: BrowserChrome, ExeReason: "not found; falling back to PATH lookup at launch", DebugPort: 1},
+	}
+
+	problems := strings.Join(ValidateSetup(configs), "\n")
+	for _, want := range []string{"edge: user-data dir /a is the browser's default", "chrome: not installed", "chrome: debug port 1 is also used by edge"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("problems missing %q:\n%s", want, problems)
+		}
+	}
+}
+
+func TestWriteLauncher_BacksUpExisting(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "applications", "fetch-edge-debug.desktop")
+	l := &Launcher{Path: path, Content: []byte("new"), Mode: 0644}
+
+	if err := WriteLauncher(l); err != nil {
+		t.Fatalf("WriteLauncher failed: %v", err)
+	}
+	if _, err := os.Stat(path + ".bak"); err == nil {
+		t.Error("no backup expected for a new launcher")
+	}
+
+	os.WriteFile(path, []byte("hand edited"), 0644)
+	if err := WriteLauncher(l); err != nil {
+		t.Fatalf("WriteLauncher failed: %v", err)
+	}
+	if backup, _ := os.ReadFile(path + ".bak"); string(backup) != "hand edited" {
+		t.Errorf("backup = %q, want the previous launcher", backup)
+	}
+	if got, _ := os.ReadFile(path); string(got) != "new" {
+		t.Errorf("launcher = %q", got)
+	}
+}
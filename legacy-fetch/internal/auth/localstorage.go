@@ -3,8 +3,6 @@ package auth
 import (
 	"encoding/json"
 	"fmt"
-
-	"github.com/go-rod/rod"
 )
 
 // The JavaScript to enumerate all localStorage entries as a JSON object
@@ -17,17 +15,55 @@ const localStorageJS = `() => {
 	return JSON.stringify(result);
 }`
 
-// ExtractLocalStorage reads all localStorage entries from a browser page.
-func ExtractLocalStorage(page *rod.Page) (map[string]string, error) {
-	result, err := page.Eval(localStorageJS)
+// readLocalStorage reads all localStorage entries from a driver's page
+func readLocalStorage(driver BrowserDriver) (map[string]string, error) {
+	jsonStr, err := driver.Eval(localStorageJS)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read localStorage: %w", err)
 	}
+	return ParseLocalStorageJSON(jsonStr)
+}
+
+// The JavaScript to enumerate all sessionStorage entries, where MSAL.js keeps
+// its token cache by default
+const sessionStorageJS = `() => {
+	const result = {};
+	for (let i = 0; i < sessionStorage.length; i++) {
+		const key = sessionStorage.key(i);
+		result[key] = sessionStorage.getItem(key);
+	}
+	return JSON.stringify(result);
+}`
 
-	jsonStr := result.Value.Str()
+// readSessionStorage reads all sessionStorage entries from a driver's page
+func readSessionStorage(driver BrowserDriver) (map[string]string, error) {
+	jsonStr, err := driver.Eval(sessionStorageJS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessionStorage: %w", err)
+	}
 	return ParseLocalStorageJSON(jsonStr)
 }
 
+// originStorage is the web storage of a security origin loaded in a tab or
+// frame other than the driver's page
+type originStorage interface {
+	LocalStorage() (map[string]string, error)
+	SessionStorage() (map[string]string, error)
+	FirebaseIndexedDB() (map[string]string, error)
+}
+
+// readOriginStorage finds the storage of a security origin, in whichever tab
+// or frame has it loaded, rather than the driver's page
+func readOriginStorage(driver BrowserDriver, origin string) (originStorage, error) {
+	reader, ok := driver.(interface {
+		OriginStorage(origin string) (originStorage, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("reading the storage of another origin needs a CDP browser")
+	}
+	return reader.OriginStorage(origin)
+}
+
 // ParseLocalStorageJSON parses the JSON string returned by the localStorage JS.
 // Exported for testing without a browser.
 func ParseLocalStorageJSON(jsonStr string) (map[string]string, error) {
@@ -0,0 +1,363 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MSAL.js credential types, as they appear in cache values. Keys use the
+// lowercase forms.
+const (
+	msalAccessToken     = "AccessToken"
+	msalAccessTokenAuth = "AccessToken_With_AuthScheme" // PoP tokens
+	msalIDToken         = "IdToken"
+	msalRefreshToken    = "RefreshToken"
+)
+
+// msalTokenKeysPrefix starts the MSAL v3 index entries that list the
+// credential keys of a client ("msal.token.keys.<client_id>")
+const msalTokenKeysPrefix = "msal.token.keys."
+
+// msalAccountKeys is the MSAL v3 index entry listing the account keys
+const msalAccountKeys = "msal.account.keys"
+
+// msalTokenKeys is the value of an MSAL v3 token key index
+type msalTokenKeys struct {
+	IDToken      []string `json:"idToken"`
+	AccessToken  []string `json:"accessToken"`
+	RefreshToken []string `json:"refreshToken"`
+}
+
+// msalTime is a cache timestamp, which MSAL writes as a string of Unix
+// seconds and some versions as a number
+type msalTime int64
+
+func (t *msalTime) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid MSAL timestamp %s", data)
+	}
+	*t = msalTime(n)
+	return nil
+}
+
+// msalEntry is an MSAL credential or account cache value. Both v2 and v3
+// store every field of the key in the value as well.
+type msalEntry struct {
+	HomeAccountID  string   `json:"homeAccountId"`
+	Environment    string   `json:"environment"`
+	CredentialType string   `json:"credentialType"`
+	ClientID       string   `json:"clientId"`
+	Secret         string   `json:"secret"`
+	Realm          string   `json:"realm"`
+	Target         string   `json:"target"`
+	TokenType      string   `json:"tokenType"`
+	ExpiresOn      msalTime `json:"expiresOn"`
+
+	// Account fields
+	LocalAccountID string `json:"localAccountId"`
+	Username       string `json:"username"`
+	Name           string `json:"name"`
+	AuthorityType  string `json:"authorityType"`
+}
+
+// MSALAccount is a signed-in account in the MSAL.js cache
+type MSALAccount struct {
+	HomeAccountID string // "<object id>.<home tenant id>"
+	Environment   string // e.g., login.microsoftonline.com
+	TenantID      string
+	Username      string
+	Name          string
+}
+
+// MSALCredential is an access, ID or refresh token in the MSAL.js cache
+type MSALCredential struct {
+	Type          string // AccessToken, IdToken or RefreshToken
+	Secret        string // The token itself
+	HomeAccountID string
+	Environment   string
+	ClientID      string
+	TenantID      string
+	Scopes        string    // Space-separated; access tokens only
+	ExpiresAt     time.Time // Zero for ID and refresh tokens
+}
+
+// Expired reports whether the credential expires within margin
+func (c *MSALCredential) Expired(margin time.Duration) bool {
+	return !c.ExpiresAt.IsZero() && time.Now().Add(margin).After(c.ExpiresAt)
+}
+
+// MSALCache is the MSAL.js token cache of an app
+type MSALCache struct {
+	Accounts    []MSALAccount
+	Credentials []*MSALCredential
+}
+
+// AccessTokens returns the cached access tokens
+func (c *MSALCache) AccessTokens() []*MSALCredential {
+	var tokens []*MSALCredential
+	for _, cred := range c.Credentials {
+		if cred.Type == msalAccessToken || cred.Type == msalAccessTokenAuth {
+			tokens = append(tokens, cred)
+		}
+	}
+	return tokens
+}
+
+// ParseMSALCache reads the MSAL.js v2/v3 token cache from browser storage.
+// MSAL keeps it in sessionStorage or localStorage depending on the app's
+// cacheLocation, so both can be passed. Credentials are keyed
+// "<homeAccountId>-<environment>-<credentialType>-<clientId>-<realm>-<target>"
+// and accounts "<homeAccountId>-<environment>-<realm>". MSAL v3 also lists
+// its live keys in msal.token.keys.<clientId> and msal.account.keys; when
+// those indexes exist, only the keys they list are read. Entries that cannot
+// be parsed are skipped and reported as problems.
+func ParseMSALCache(storages ...map[string]string) (*MSALCache, []error) {
+	cache := &MSALCache{}
+	var problems []error
+
+	for _, storage := range storages {
+		keys, indexed := msalIndexedKeys(storage)
+		for key, value := range storage {
+			credType := msalKeyCredentialType(key)
+			if credType == "" && !keys.account(key, value) {
+				continue
+			}
+			if credType != "" && indexed != nil && !indexed[key] {
+				continue // Left behind by MSAL v3, which no longer uses it
+			}
+
+			var entry msalEntry
+			if err := json.Unmarshal([]byte(value), &entry); err != nil {
+				problems = append(problems, fmt.Errorf("failed to parse MSAL cache entry %q: %w", key, err))
+				continue
+			}
+
+			if credType == "" {
+				cache.Accounts = append(cache.Accounts, MSALAccount{
+					HomeAccountID: entry.HomeAccountID,
+					Environment:   entry.Environment,
+					TenantID:      entry.Realm,
+					Username:      entry.Username,
+					Name:          entry.Name,
+				})
+				continue
+			}
+
+			if entry.Secret == "" {
+				problems = append(problems, fmt.Errorf("MSAL cache entry %q has no secret", key))
+				continue
+			}
+			cred := &MSALCredential{
+				Type:          entry.CredentialType,
+				Secret:        entry.Secret,
+				HomeAccountID: entry.HomeAccountID,
+				Environment:   entry.Environment,
+				ClientID:      entry.ClientID,
+				TenantID:      entry.Realm,
+				Scopes:        entry.Target,
+			}
+			if cred.Type == "" {
+				cred.Type = credType
+			}
+			if entry.ExpiresOn > 0 {
+				cred.ExpiresAt = time.Unix(int64(entry.ExpiresOn), 0)
+			}
+			cache.Credentials = append(cache.Credentials, cred)
+		}
+	}
+
+	sort.Slice(cache.Accounts, func(i, j int) bool { return cache.Accounts[i].Username < cache.Accounts[j].Username })
+	sort.Slice(cache.Credentials, func(i, j int) bool {
+		a, b := cache.Credentials[i], cache.Credentials[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.ClientID != b.ClientID {
+			return a.ClientID < b.ClientID
+		}
+		if a.TenantID != b.TenantID {
+			return a.TenantID < b.TenantID
+		}
+		return a.Scopes < b.Scopes
+	})
+	return cache, problems
+}
+
+// msalKeys holds what a storage's MSAL v3 indexes say about its keys
+type msalKeys struct {
+	accounts map[string]bool
+}
+
+// account reports whether a key holds an MSAL account. v3 lists them in
+// msal.account.keys; for v2 the value is recognized by its fields.
+func (k msalKeys) account(key, value string) bool {
+	if k.accounts != nil {
+		return k.accounts[key]
+	}
+	if !strings.HasPrefix(value, "{") || !strings.Contains(value, `"authorityType"`) {
+		return false
+	}
+	var entry msalEntry
+	return json.Unmarshal([]byte(value), &entry) == nil && entry.HomeAccountID != "" && entry.CredentialType == ""
+}
+
+// msalIndexedKeys reads the MSAL v3 key indexes. indexed holds the listed
+// credential keys, and is nil for a v2 cache, which has no index.
+func msalIndexedKeys(storage map[string]string) (keys msalKeys, indexed map[string]bool) {
+	for key, value := range storage {
+		switch {
+		case strings.HasPrefix(key, msalTokenKeysPrefix):
+			var index msalTokenKeys
+			if json.Unmarshal([]byte(value), &index) != nil {
+				continue
+			}
+			if indexed == nil {
+				indexed = map[string]bool{}
+			}
+			for _, list := range [][]string{index.IDToken, index.AccessToken, index.RefreshToken} {
+				for _, k := range list {
+					indexed[k] = true
+				}
+			}
+		case key == msalAccountKeys:
+			var accounts []string
+			if json.Unmarshal([]byte(value), &accounts) != nil {
+				continue
+			}
+			keys.accounts = map[string]bool{}
+			for _, k := range accounts {
+				keys.accounts[k] = true
+			}
+		}
+	}
+	return keys, indexed
+}
+
+// msalKeyCredentialType returns the credential type named by an MSAL
+// credential key, or "" for any other key
+func msalKeyCredentialType(key string) string {
+	for _, credType := range []string{msalAccessTokenAuth, msalAccessToken, msalIDToken, msalRefreshToken} {
+		if strings.Contains(key, "-"+strings.ToLower(credType)+"-") {
+			return credType
+		}
+	}
+	return ""
+}
+
+// MSALSelector picks an MSAL access token. Empty fields match any token.
+type MSALSelector struct {
+	Resource string // e.g., https://graph.microsoft.com or api://<app id>
+	Scope    string // Space-separated scopes the token must include
+	Tenant   string // Tenant (realm) ID the token was issued in
+}
+
+// Matches reports whether an access token fits the selector. The resource
+// matches the token's aud claim, or the resource prefix of its scopes; MSAL
+// caches Microsoft Graph scopes without one (User.Read). Scopes match
+// case-insensitively, and also by their short form without the resource.
+func (s MSALSelector) Matches(c *MSALCredential) bool {
+	if s.Tenant != "" && !strings.EqualFold(s.Tenant, c.TenantID) {
+		return false
+	}
+
+	scopes := strings.Fields(c.Scopes)
+	if s.Resource != "" && !msalAudienceMatches(c.Secret, s.Resource) {
+		resource := strings.TrimSuffix(s.Resource, "/") + "/"
+		found := false
+		for _, scope := range scopes {
+			if strings.HasPrefix(strings.ToLower(scope), strings.ToLower(resource)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, want := range strings.Fields(s.Scope) {
+		found := false
+		for _, scope := range scopes {
+			if strings.EqualFold(scope, want) || strings.HasSuffix(strings.ToLower(scope), "/"+strings.ToLower(want)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// msalAudienceMatches reports whether an access token is a JWT issued for
+// resource. Tokens for Microsoft APIs that are not JWTs never match.
+func msalAudienceMatches(token, resource string) bool {
+	jwt, err := DecodeJWT(token)
+	if err != nil {
+		return false
+	}
+	for _, aud := range jwt.Audiences() {
+		if strings.EqualFold(strings.TrimSuffix(aud, "/"), strings.TrimSuffix(resource, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the selector for error messages
+func (s MSALSelector) String() string {
+	var parts []string
+	if s.Resource != "" {
+		parts = append(parts, fmt.Sprintf("resource %q", s.Resource))
+	}
+	if s.Scope != "" {
+		parts = append(parts, fmt.Sprintf("scope %q", s.Scope))
+	}
+	if s.Tenant != "" {
+		parts = append(parts, fmt.Sprintf("tenant %q", s.Tenant))
+	}
+	if len(parts) == 0 {
+		return "any resource"
+	}
+	return strings.Join(parts, " and ")
+}
+
+// SelectMSALToken returns the first live MSAL access token matching
+// selector, in the order of ParseMSALCache. Expired tokens are never
+// returned, since MSAL would not use them either.
+func SelectMSALToken(cache *MSALCache, selector MSALSelector) (*MSALCredential, error) {
+	tokens := cache.AccessTokens()
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("no MSAL access token found in storage")
+	}
+
+	var expired *MSALCredential
+	for _, token := range tokens {
+		if !selector.Matches(token) {
+			continue
+		}
+		if !token.Expired(0) {
+			return token, nil
+		}
+		expired = token
+	}
+	if expired != nil {
+		return nil, fmt.Errorf("the MSAL access token for %s expired at %s", selector, expired.ExpiresAt.Format(time.RFC3339))
+	}
+
+	var available []string
+	for _, token := range tokens {
+		available = append(available, fmt.Sprintf("%s [%s]", token.TenantID, token.Scopes))
+	}
+	return nil, fmt.Errorf("no MSAL access token for %s (available: %s)", selector, strings.Join(available, ", "))
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	msalHome   = "0f2e-uid.72f9-utid"
+	msalClient = "6b3c9c3e-4f7d-4d8e-9f3a-1c2d3e4f5a6b"
+	msalTenant = "72f988bf-86f1-41af-91ab-2d7cd011db47"
+)
+
+// msalGraphToken is a Microsoft Graph access token; MSAL caches its scopes
+// without the resource, so only its aud claim names Graph
+var msalGraphToken = testJWT(`{"aud":"https://graph.microsoft.com","scp":"User.Read Mail.Read"}`)
+
+func msalAccessTokenEntry(secret, target string, expiresOn int64) (string, string) {
+	key := strings.ToLower(msalHome + "-login.microsoftonline.com-accesstoken-" + msalClient + "-" + msalTenant + "-" + target)
+	value := `{"homeAccountId":"` + msalHome + `","environment":"login.microsoftonline.com","credentialType":"AccessToken",` +
+		`"clientId":"` + msalClient + `","secret":"` + secret + `","realm":"` + msalTenant + `","target":"` + target + `",` +
+		`"cachedAt":"1700000000","expiresOn":"` + strconv.FormatInt(expiresOn, 10) + `","tokenType":"Bearer"}`
+	return key, value
+}
+
+func msalV2Storage() map[string]string {
+	live := time.Now().Add(time.Hour).Unix()
+	storage := map[string]string{
+		msalHome + "-login.microsoftonline.com-" + msalTenant: `{"homeAccountId":"` + msalHome + `","environment":"login.microsoftonline.com",` +
+			`"realm":"` + msalTenant + `","localAccountId":"0f2e","username":"ada@example.com","name":"Ada","authorityType":"MSSTS"}`,
+		msalHome + "-login.microsoftonline.com-idtoken-" + msalClient + "-" + msalTenant + "-": `{"homeAccountId":"` + msalHome + `",` +
+			`"environment":"login.microsoftonline.com","credentialType":"IdToken","clientId":"` + msalClient + `","secret":"id-token","realm":"` + msalTenant + `"}`,
+		msalHome + "-login.microsoftonline.com-refreshtoken-" + msalClient + "--": `{"homeAccountId":"` + msalHome + `",` +
+			`"environment":"login.microsoftonline.com","credentialType":"RefreshToken","clientId":"` + msalClient + `","secret":"refresh-token"}`,
+		"msal.interaction.status": "",
+		"unrelated":               `{"authorityType":"x"}`,
+	}
+	for _, e := range []struct {
+		secret, target string
+		expiresOn      int64
+	}{
+		{msalGraphToken, "User.Read Mail.Read openid profile", live},
+		{"api-token", "api://orders/Orders.Read", live},
+		{"old-api-token", "api://orders/Orders.Write", 1700000000},
+	} {
+		key, value := msalAccessTokenEntry(e.secret, e.target, e.expiresOn)
+		storage[key] = value
+	}
+	return storage
+}
+
+func TestParseMSALCache_V2(t *testing.T) {
+	cache, problems := ParseMSALCache(map[string]string{}, msalV2Storage())
+	if len(problems) != 0 {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+	if len(cache.Accounts) != 1 || cache.Accounts[0].Username != "ada@example.com" || cache.Accounts[0].TenantID != msalTenant {
+		t.Errorf("unexpected accounts %+v", cache.Accounts)
+	}
+	if len(cache.Credentials) != 5 || len(cache.AccessTokens()) != 3 {
+		t.Errorf("expected 5 credentials with 3 access tokens, got %+v", cache.Credentials)
+	}
+}
+
+func TestParseMSALCache_V3Index(t *testing.T) {
+	storage := msalV2Storage()
+	graphKey, _ := msalAccessTokenEntry(msalGraphToken, "User.Read Mail.Read openid profile", 0)
+	storage["msal.token.keys."+msalClient] = `{"idToken":[],"accessToken":["` + graphKey + `"],"refreshToken":[]}`
+	storage["msal.account.keys"] = `["` + msalHome + `-login.microsoftonline.com-` + msalTenant + `"]`
+
+	cache, _ := ParseMSALCache(storage)
+	if tokens := cache.AccessTokens(); len(tokens) != 1 || tokens[0].Secret != msalGraphToken {
+		t.Errorf("expected only the indexed access token, got %+v", tokens)
+	}
+	if len(cache.Accounts) != 1 {
+		t.Errorf("expected the indexed account, got %+v", cache.Accounts)
+	}
+}
+
+func TestSelectMSALToken(t *testing.T) {
+	cache, _ := ParseMSALCache(msalV2Storage())
+
+	tests := []struct {
+		name     string
+		selector MSALSelector
+		want     string
+		wantErr  string
+	}{
+		{"resource", MSALSelector{Resource: "api://orders"}, "api-token", ""},
+		{"short scope", MSALSelector{Scope: "mail.read"}, msalGraphToken, ""},
+		{"Graph resource by aud", MSALSelector{Resource: "https://graph.microsoft.com"}, msalGraphToken, ""},
+		{"Graph resource and short scope", MSALSelector{Resource: "https://graph.microsoft.com/", Scope: "User.Read"}, msalGraphToken, ""},
+		{"full scope and tenant", MSALSelector{Scope: "api://orders/Orders.Read", Tenant: strings.ToUpper(msalTenant)}, "api-token", ""},
+		{"expired", MSALSelector{Scope: "Orders.Write"}, "", "expired"},
+		{"other tenant", MSALSelector{Tenant: "common"}, "", "available"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := SelectMSALToken(cache, tt.selector)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if token.Secret != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, token.Secret)
+			}
+		})
+	}
+}
+
+func TestParseMSALCache_SkipsBadEntries(t *testing.T) {
+	storage := map[string]string{
+		msalHome + "-login.microsoftonline.com-accesstoken-" + msalClient + "-" + msalTenant + "-user.read": `{not json`,
+	}
+	cache, problems := ParseMSALCache(storage)
+	if len(cache.Credentials) != 0 || len(problems) != 1 {
+		t.Errorf("expected the malformed entry to be reported, got %+v, %v", cache.Credentials, problems)
+	}
+	if _, err := SelectMSALToken(cache, MSALSelector{}); err == nil {
+		t.Error("expected an error without access tokens")
+	}
+}
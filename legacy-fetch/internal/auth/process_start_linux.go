@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of /proc/<pid>/stat times. It is 100 on
+// every Linux architecture fetch runs on.
+const clockTicks = 100
+
+// processStartTime returns when a process started, from its start time in
+// clock ticks after boot in /proc/<pid>/stat and the boot time in /proc/stat
+func processStartTime(pid int) (time.Time, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read process %d: %w", pid, err)
+	}
+	// The command name in parentheses may hold spaces; fields follow it,
+	// starting with field 3 (state). starttime is field 22.
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	if len(fields) < 20 {
+		return time.Time{}, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	ticks, err := strconv.ParseInt(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected /proc/%d/stat start time: %w", pid, err)
+	}
+
+	data, err = os.ReadFile("/proc/stat")
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read boot time: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if btime, ok := strings.CutPrefix(line, "btime "); ok {
+			boot, err := strconv.ParseInt(strings.TrimSpace(btime), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("unexpected boot time %q: %w", btime, err)
+			}
+			return time.Unix(boot, 0).Add(time.Duration(ticks) * time.Second / clockTicks), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no boot time in /proc/stat")
+}
@@ -0,0 +1,28 @@
+//go:build !windows && !linux
+
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// processStartTime returns when a process started, as ps reports it. ps
+// prints the time to the second in the C locale's date format.
+func processStartTime(pid int) (time.Time, error) {
+	cmd := exec.Command("ps", "-o", "lstart=", "-p", strconv.Itoa(pid))
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read start time of process %d: %w", pid, err)
+	}
+	start, err := time.ParseInLocation("Mon Jan 2 15:04:05 2006", strings.Join(strings.Fields(string(out)), " "), time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected start time of process %d: %w", pid, err)
+	}
+	return start, nil
+}
@@ -0,0 +1,36 @@
+//go:build !windows
+
+package auth
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// pipeSupported reports whether a browser can inherit the debugging pipe fds
+const pipeSupported = true
+
+// processAlive checks if a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	// EPERM means the process exists but belongs to someone else
+	return err == nil || err == syscall.EPERM
+}
+
+// terminateProcess asks a process to exit
+func terminateProcess(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(syscall.SIGTERM)
+}
+
+// detachProcess starts the browser in its own session so it outlives fetch
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
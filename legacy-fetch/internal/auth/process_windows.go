@@ -0,0 +1,66 @@
+//go:build windows
+
+package auth
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// stillActive is the exit code Windows reports for a running process
+const stillActive = 259
+
+// pipeSupported reports whether a browser can inherit the debugging pipe fds.
+// os/exec cannot pass extra file descriptors on Windows.
+const pipeSupported = false
+
+// processAlive checks if a process with the given PID exists
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// processStartTime returns when a process was created
+func processStartTime(pid int) (time.Time, error) {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to open process %d: %w", pid, err)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var creation, exit, kernel, user syscall.Filetime
+	if err := syscall.GetProcessTimes(handle, &creation, &exit, &kernel, &user); err != nil {
+		return time.Time{}, fmt.Errorf("failed to read start time of process %d: %w", pid, err)
+	}
+	return time.Unix(0, creation.Nanoseconds()), nil
+}
+
+// terminateProcess asks a process to exit
+// Windows has no SIGTERM, so this is a hard kill
+func terminateProcess(pid int) error {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return proc.Kill()
+}
+
+// detachProcess starts the browser in its own process group so it outlives fetch
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP}
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cloneSkipDirs are profile subdirectories that only hold caches the
+// browser rebuilds; copying them is slow and gains nothing
+var cloneSkipDirs = map[string]bool{
+	"Cache":                          true,
+	"Code Cache":                     true,
+	"GPUCache":                       true,
+	"DawnCache":                      true,
+	"DawnGraphiteCache":              true,
+	"DawnWebGPUCache":                true,
+	"GraphiteDawnCache":              true,
+	"GrShaderCache":                  true,
+	"ShaderCache":                    true,
+	"Crashpad":                       true,
+	"Crash Reports":                  true,
+	"blob_storage":                   true,
+	"optimization_guide_model_store": true,
+	filepath.Join("Service Worker", "CacheStorage"): true,
+	filepath.Join("Service Worker", "ScriptCache"):  true,
+}
+
+// cloneSkipFiles are lock files that must not be carried over, or the
+// cloned profile would look in use by the source browser
+var cloneSkipFiles = map[string]bool{
+	"SingletonLock":   true,
+	"SingletonSocket": true,
+	"SingletonCookie": true,
+	"lockfile":        true,
+	"LOCK":            true,
+}
+
+// CloneResult summarizes a profile clone
+type CloneResult struct {
+	Profile BrowserProfile // The profile that was copied
+	Files   int
+	Bytes   int64
+	Skipped int // Cache directories and lock files left behind
+}
+
+// CloneProfile copies a Chromium profile, together with the Local State file
+// that holds its display name and cookie encryption key, from sourceDir into
+// destDir. profileName selects the profile as for ResolveProfile; empty means
+// the source browser's default profile. It refuses to run while either
+// user-data dir is in use, and to overwrite an initialized destination
+// unless force is set.
+func CloneProfile(sourceDir, profileName, destDir string, force bool) (*CloneResult, error) {
+	profiles, err := ListProfiles(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := ""
+	if profileName != "" {
+		if dir, err = matchProfile(profiles, profileName); err != nil {
+			return nil, err
+		}
+	}
+
+	var profile BrowserProfile
+	for _, p := range profiles {
+		if p.Directory == dir || (dir == "" && p.LastUsed) {
+			profile = p
+		}
+	}
+	if profile.Directory == "" {
+		return nil, fmt.Errorf("no default profile in %s; name one to clone", sourceDir)
+	}
+
+	source, dest := localPath(sourceDir), localPath(destDir)
+	if _, err := os.Stat(filepath.Join(source, profile.Directory)); err != nil {
+		return nil, fmt.Errorf("profile %q is listed in Local State but %s has no %s directory", profile.Name, sourceDir, profile.Directory)
+	}
+	if holder, locked := userDataDirLocked(source); locked {
+		return nil, fmt.Errorf("%s is in use by a running browser (%s); quit it completely, including background or startup boost processes, and retry",
+			sourceDir, holder)
+	}
+	if holder, locked := userDataDirLocked(dest); locked {
+		return nil, fmt.Errorf("%s is in use by a running browser (%s); stop it first with \"fetch browser stop\"", destDir, holder)
+	}
+
+	if _, err := os.Stat(filepath.Join(dest, "Local State")); err == nil && !force {
+		return nil, fmt.Errorf("%s already holds a browser profile; use --force to overwrite it", destDir)
+	}
+
+	if err := os.MkdirAll(dest, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create user-data dir: %w", err)
+	}
+
+	result := &CloneResult{Profile: profile}
+	n, err := copyFile(filepath.Join(source, "Local State"), filepath.Join(dest, "Local State"))
+	if err != nil {
+		return nil, err
+	}
+	result.Files++
+	result.Bytes += n
+
+	if err := copyProfileTree(filepath.Join(source, profile.Directory), filepath.Join(dest, profile.Directory), result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// localPath converts a user-data dir as handed to the browser into a path
+// fetch can open, which differs only for Windows browsers under WSL
+func localPath(userDataDir string) string {
+	if IsWSL() {
+		return wslMountPath(userDataDir)
+	}
+	return userDataDir
+}
+
+// userDataDirLocked reports whether a browser holds a user-data dir, and
+// describes the holder. Linux and macOS browsers leave a SingletonLock
+// symlink to "host-pid"; Windows browsers keep "lockfile" open exclusively.
+func userDataDirLocked(dir string) (string, bool) {
+	singleton := filepath.Join(dir, "SingletonLock")
+	if target, err := os.Readlink(singleton); err == nil {
+		sep := strings.LastIndex(target, "-")
+		host, _ := os.Hostname()
+		if sep < 0 || target[:sep] != host {
+			return target, true
+		}
+		pid, err := strconv.Atoi(target[sep+1:])
+		if err != nil || processAlive(pid) {
+			return "PID " + target[sep+1:], true
+		}
+		// Stale lock left by a crashed browser
+	} else if _, err := os.Lstat(singleton); err == nil {
+		return "SingletonLock", true
+	}
+
+	lockfile := filepath.Join(dir, "lockfile")
+	if _, err := os.Stat(lockfile); err == nil {
+		f, err := os.OpenFile(lockfile, os.O_RDWR, 0)
+		if err != nil {
+			return "lockfile held open", true
+		}
+		f.Close()
+	}
+	return "", false
+}
+
+// copyProfileTree copies a profile directory, leaving out caches and locks
+func copyProfileTree(source, dest string, result *CloneResult) error {
+	return filepath.Walk(source, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return fmt.Errorf("failed to read profile: %w", err)
+		}
+
+		rel, err := filepath.Rel(source, p)
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			if cloneSkipDirs[rel] {
+				result.Skipped++
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(filepath.Join(dest, rel), 0700)
+		}
+
+		if cloneSkipFiles[info.Name()] || !info.Mode().IsRegular() {
+			result.Skipped++
+			return nil
+		}
+		n, err := copyFile(p, filepath.Join(dest, rel))
+		if err != nil {
+			return err
+		}
+		result.Files++
+		result.Bytes += n
+		return nil
+	})
+}
+
+// copyFile copies one file and returns the number of bytes copied
+func copyFile(source, dest string) (int64, error) {
+	in, err := os.Open(source)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s: %w", source, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", dest, err)
+	}
+
+	n, err := io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy %s: %w", source, err)
+	}
+	return n, nil
+}
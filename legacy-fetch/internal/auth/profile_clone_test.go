@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeProfileFixture creates a user-data dir with the testLocalState
+// profiles and some content in Profile 2
+func writeProfileFixture(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"Local State":                                testLocalState,
+		"Profile 2/Cookies":                          "cookies",
+		"Profile 2/Preferences":                      "{}",
+		"Profile 2/Login Data":                       "logins",
+		"Profile 2/Local Storage/leveldb/000003.log": "storage",
+		"Profile 2/Local Storage/leveldb/LOCK":       "",
+		"Profile 2/Cache/Cache_Data/data_0":          "cached",
+		"Profile 2/Service Worker/CacheStorage/x":    "cached",
+		"Profile 2/Service Worker/Database/CURRENT":  "sw",
+		"Profile 1/Cookies":                          "other profile",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCloneProfile(t *testing.T) {
+	source := writeProfileFixture(t)
+	dest := filepath.Join(t.TempDir(), "EdgeDebug")
+
+	result, err := CloneProfile(source, "Test tenant A", dest, false)
+	if err != nil {
+		t.Fatalf("CloneProfile failed: %v", err)
+	}
+	if result.Profile.Directory != "Profile 2" {
+		t.Errorf("cloned %q, want Profile 2", result.Profile.Directory)
+	}
+
+	for _, name := range []string{"Local State", "Profile 2/Cookies", "Profile 2/Preferences", "Profile 2/Login Data",
+		"Profile 2/Local Storage/leveldb/000003.log", "Profile 2/Service Worker/Database/CURRENT"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err != nil {
+			t.Errorf("expected %s to be copied: %v", name, err)
+		}
+	}
+	for _, name := range []string{"Profile 2/Cache", "Profile 2/Service Worker/CacheStorage",
+		"Profile 2/Local Storage/leveldb/LOCK", "Profile 1"} {
+		if _, err := os.Stat(filepath.Join(dest, filepath.FromSlash(name))); err == nil {
+			t.Errorf("expected %s to be left behind", name)
+		}
+	}
+	if result.Skipped != 3 {
+		t.Errorf("Skipped = %d, want 3", result.Skipped)
+	}
+
+	if _, err := CloneProfile(source, "Test tenant A", dest, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected an initialized destination to need --force, got %v", err)
+	}
+	if _, err := CloneProfile(source, "Test tenant A", dest, true); err != nil {
+		t.Errorf("CloneProfile with force failed: %v", err)
+	}
+}
+
+func TestCloneProfile_DefaultProfile(t *testing.T) {
+	source := writeProfileFixture(t)
+
+	result, err := CloneProfile(source, "", filepath.Join(t.TempDir(), "debug"), false)
+	if err != nil {
+		t.Fatalf("CloneProfile failed: %v", err)
+	}
+	if result.Profile.Directory != "Profile 1" {
+		t.Errorf("cloned %q, want the last used Profile 1", result.Profile.Directory)
+	}
+}
+
+func TestCloneProfile_RefusesLockedSource(t *testing.T) {
+	source := writeProfileFixture(t)
+	host, _ := os.Hostname()
+	if err := os.Symlink(fmt.Sprintf("%s-%d", host, os.Getpid()), filepath.Join(source, "SingletonLock")); err != nil {
+		t.Skipf("cannot create SingletonLock symlink: %v", err)
+	}
+
+	_, err := CloneProfile(source, "Test tenant A", filepath.Join(t.TempDir(), "debug"), false)
+	if err == nil || !strings.Contains(err.Error(), "in use") {
+		t.Errorf("expected locked profile error, got %v", err)
+	}
+
+	// A lock whose process is gone is stale and ignored
+	os.Remove(filepath.Join(source, "SingletonLock"))
+	os.Symlink(host+"-0", filepath.Join(source, "SingletonLock"))
+	if _, err := CloneProfile(source, "Test tenant A", filepath.Join(t.TempDir(), "debug"), false); err != nil {
+		t.Errorf("expected stale lock to be ignored, got %v", err)
+	}
+}
@@ -77,8 +77,87 @@ func (s *SessionManager) LoadCookies(host string) ([]*http.Cookie, error) {
 	return cookies, nil
 }
 
+// SaveTokens stores the Auth0 tokens captured for a host alongside its
+// cookies, in a tokens subdirectory so they are not listed as sessions
+func (s *SessionManager) SaveTokens(host string, tokens *Auth0Tokens) error {
+	if err := os.MkdirAll(filepath.Dir(s.getTokenPath(host)), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+
+	if err := os.WriteFile(s.getTokenPath(host), data, 0600); err != nil {
+		return fmt.Errorf("failed to write token file: %w", err)
+	}
+	return nil
+}
+
+// LoadTokens loads the Auth0 tokens stored for a host
+// Returns nil if none are stored (not an error)
+func (s *SessionManager) LoadTokens(host string) (*Auth0Tokens, error) {
+	data, err := os.ReadFile(s.getTokenPath(host))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token file: %w", err)
+	}
+
+	var tokens Auth0Tokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tokens: %w", err)
+	}
+	return &tokens, nil
+}
+
+// SaveFirebaseUser stores the Firebase user captured for a host alongside
+// its cookies, next to its Auth0 tokens
+func (s *SessionManager) SaveFirebaseUser(host string, user *FirebaseUser) error {
+	if err := os.MkdirAll(filepath.Dir(s.getFirebasePath(host)), 0700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(user, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal Firebase user: %w", err)
+	}
+
+	if err := os.WriteFile(s.getFirebasePath(host), data, 0600); err != nil {
+		return fmt.Errorf("failed to write Firebase user file: %w", err)
+	}
+	return nil
+}
+
+// LoadFirebaseUser loads the Firebase user stored for a host
+// Returns nil if none is stored (not an error)
+func (s *SessionManager) LoadFirebaseUser(host string) (*FirebaseUser, error) {
+	data, err := os.ReadFile(s.getFirebasePath(host))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Firebase user file: %w", err)
+	}
+
+	var user FirebaseUser
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Firebase user: %w", err)
+	}
+	return &user, nil
+}
+
 // Clear removes the cached session for a specific host
 func (s *SessionManager) Clear(host string) error {
+	// Stored tokens belong to the session
+	for _, path := range []string{s.getTokenPath(host), s.getFirebasePath(host)} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete token file: %w", err)
+		}
+	}
+
 	cookiePath := s.getCookiePath(host)
 
 	// Check if file exists
@@ -125,6 +204,16 @@ func (s *SessionManager) ListSessions() ([]string, error) {
 	return hosts, nil
 }
 
+// getTokenPath returns the file path for a host's stored tokens
+func (s *SessionManager) getTokenPath(host string) string {
+	return filepath.Join(s.cacheDir, "tokens", fmt.Sprintf("%s.json", host))
+}
+
+// getFirebasePath returns the file path for a host's stored Firebase user
+func (s *SessionManager) getFirebasePath(host string) string {
+	return filepath.Join(s.cacheDir, "tokens", fmt.Sprintf("%s.firebase.json", host))
+}
+
 // getCookiePath returns the file path for a host's cookie cache
 func (s *SessionManager) getCookiePath(host string) string {
 	return filepath.Join(s.cacheDir, fmt.Sprintf("%s.json", host))
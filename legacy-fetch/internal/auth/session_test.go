@@ -269,3 +269,51 @@ func TestCookieSerialization(t *testing.T) {
 		t.Errorf("SameSite mismatch: expected %v, got %v", original.SameSite, restored.SameSite)
 	}
 }
+
+func TestSessionManager_Tokens(t *testing.T) {
+	sm := &SessionManager{cacheDir: t.TempDir()}
+
+	if tokens, err := sm.LoadTokens("app.example.com"); err != nil || tokens != nil {
+		t.Fatalf("expected no tokens, got %+v, %v", tokens, err)
+	}
+
+	saved := &Auth0Tokens{AccessToken: "a", RefreshToken: "r", ClientID: "c", TokenURL: "https://tenant.auth0.com/oauth/token"}
+	if err := sm.SaveTokens("app.example.com", saved); err != nil {
+		t.Fatalf("SaveTokens failed: %v", err)
+	}
+	loaded, err := sm.LoadTokens("app.example.com")
+	if err != nil || loaded.RefreshToken != "r" || loaded.TokenURL != saved.TokenURL {
+		t.Errorf("LoadTokens = %+v, %v", loaded, err)
+	}
+
+	if hosts, _ := sm.ListSessions(); len(hosts) != 0 {
+		t.Errorf("expected tokens not to be listed as sessions, got %v", hosts)
+	}
+
+	if err := sm.Clear("app.example.com"); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if tokens, _ := sm.LoadTokens("app.example.com"); tokens != nil {
+		t.Error("expected Clear to remove the stored tokens")
+	}
+}
+
+func TestSessionManager_FirebaseUser(t *testing.T) {
+	sm := &SessionManager{cacheDir: t.TempDir()}
+
+	saved := &FirebaseUser{APIKey: "AIzaKey1", IDToken: "id", RefreshToken: "r"}
+	if err := sm.SaveFirebaseUser("portal.example.com", saved); err != nil {
+		t.Fatalf("SaveFirebaseUser failed: %v", err)
+	}
+	loaded, err := sm.LoadFirebaseUser("portal.example.com")
+	if err != nil || loaded == nil || loaded.RefreshToken != "r" {
+		t.Errorf("LoadFirebaseUser = %+v, %v", loaded, err)
+	}
+
+	if err := sm.Clear("portal.example.com"); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if user, _ := sm.LoadFirebaseUser("portal.example.com"); user != nil {
+		t.Error("expected Clear to remove the stored Firebase user")
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// silentLoginDeadline bounds a silent login; SSO that works at all finishes
+// well within it
+const silentLoginDeadline = 20 * time.Second
+
+// errCredentialPrompt means the identity provider asked for input, so the
+// login cannot complete silently
+var errCredentialPrompt = fmt.Errorf("the identity provider is asking for credentials")
+
+// credentialPromptJS reports whether the page is asking the user for
+// something: a username or password field, or the Microsoft account picker
+// and "Stay signed in?" prompts
+const credentialPromptJS = `() => {
+	const prompt = document.querySelector(
+		'input[type=password], input[type=email], input[name=loginfmt], input[autocomplete=username], #tilesHolder, #KmsiCheckboxField');
+	return String(!!prompt && prompt.offsetParent !== null);
+}`
+
+// SilentAuthenticate logs in to targetURL without showing anything to the
+// user, relying on the SSO cookies in the browser profile. A browser fetch
+// launches for it is headless on the usual debug profile; a debug browser
+// that is already running gets a background tab instead, since a profile
+// can only be open in one browser. It fails as soon as the identity provider
+// prompts for credentials, and after silentLoginDeadline.
+func (b *BrowserAuth) SilentAuthenticate(targetURL string) error {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return fmt.Errorf("failed to parse target URL: %w", err)
+	}
+	host := parsedURL.Host
+
+	config, err := b.browserConfig()
+	if err != nil {
+		return fmt.Errorf("failed to get browser config: %w", err)
+	}
+	if config.Protocol == ProtocolWebDriver {
+		return fmt.Errorf("%s is driven over WebDriver, which cannot log in silently", config.Type)
+	}
+	config.Silent = true
+
+	driver, err := b.openDriver(config)
+	if err != nil {
+		return fmt.Errorf("failed to get browser: %w", err)
+	}
+	defer driver.Close()
+
+	if err := driver.Open(targetURL); err != nil {
+		return err
+	}
+
+	if err := b.waitForSilentLogin(driver, host); err != nil {
+		return err
+	}
+
+	cookies, err := driver.Cookies()
+	if err != nil {
+		return fmt.Errorf("failed to extract cookies: %w", err)
+	}
+	if len(cookies) == 0 {
+		return fmt.Errorf("no cookies captured")
+	}
+	if err := b.sessionManager.SaveCookies(host, cookies); err != nil {
+		return fmt.Errorf("failed to save cookies: %w", err)
+	}
+
+	progressf(b.progress, "Silently re-authenticated %s (%d cookies)\n", host, len(cookies))
+	return nil
+}
+
+// waitForSilentLogin waits for the login to settle on host, like
+// waitForLogin, but gives up on a credential prompt or at the deadline
+func (b *BrowserAuth) waitForSilentLogin(driver BrowserDriver, host string) error {
+	pollInterval, stableThreshold, deadline := loginPollInterval, loginStableThreshold, silentLoginDeadline
+	if b.pollInterval > 0 {
+		pollInterval, stableThreshold = b.pollInterval, b.stableThreshold
+		deadline = 20 * b.stableThreshold
+	}
+
+	tracker := &loginTracker{host: host, stableThreshold: stableThreshold, popups: popupWatcher{progress: b.progress}}
+	for start := time.Now(); time.Since(start) < deadline; {
+		time.Sleep(pollInterval)
+
+		if prompt, err := driver.Eval(credentialPromptJS); err == nil && prompt == "true" {
+			current, _ := driver.URL()
+			return fmt.Errorf("%w on %s", errCredentialPrompt, hostOf(current))
+		}
+
+		settled, err := tracker.poll(driver)
+		if err != nil {
+			return fmt.Errorf("lost the login page: %w", err)
+		}
+		if settled {
+			return nil
+		}
+	}
+	return fmt.Errorf("login did not complete within %s", deadline)
+}
+
+// hostOf returns the host of a URL, or the URL itself if it has none
+func hostOf(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return rawURL
+}
+
+// openSilent opens a page for a silent login: in the background, so a
+// running browser does not come to the front, and with a headless browser's
+// user agent masked, since identity providers may refuse "HeadlessChrome"
+func (d *cdpDriver) openSilent(targetURL string) error {
+	page, err := d.browser.Page(proto.TargetCreateTarget{URL: "about:blank", Background: true})
+	if err != nil {
+		return fmt.Errorf("failed to open page: %w", err)
+	}
+	d.page = page
+
+	if version, err := (proto.BrowserGetVersion{}).Call(d.browser); err == nil && strings.Contains(version.UserAgent, "HeadlessChrome") {
+		userAgent := strings.Replace(version.UserAgent, "HeadlessChrome", "Chrome", 1)
+		_ = proto.NetworkSetUserAgentOverride{UserAgent: userAgent}.Call(page)
+	}
+
+	if err := page.Navigate(targetURL); err != nil {
+		return fmt.Errorf("failed to open %s: %w", targetURL, err)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedDriver is a BrowserDriver whose page walks through urls, one per
+// URL call, and shows a credential prompt on the URLs in prompts
+type scriptedDriver struct {
+	urls    []string
+	current string
+	prompts map[string]bool
+}
+
+func (d *scriptedDriver) Open(targetURL string) error { d.current = targetURL; return nil }
+
+func (d *scriptedDriver) URL() (string, error) {
+	if len(d.urls) > 0 {
+		d.current, d.urls = d.urls[0], d.urls[1:]
+	}
+	return d.current, nil
+}
+
+func (d *scriptedDriver) Cookies() ([]*http.Cookie, error) { return nil, nil }
+
+func (d *scriptedDriver) Eval(js string) (string, error) {
+	if js == credentialPromptJS && d.prompts[d.current] {
+		return "true", nil
+	}
+	return "false", nil
+}
+
+func (d *scriptedDriver) Popups() ([]string, error) { return nil, nil }
+
+func (d *scriptedDriver) Close() error { return nil }
+
+func TestWaitForSilentLogin(t *testing.T) {
+	b := &BrowserAuth{pollInterval: time.Millisecond, stableThreshold: 10 * time.Millisecond}
+
+	sso := &scriptedDriver{urls: []string{
+		"https://login.microsoftonline.com/common/oauth2/authorize",
+		"https://app.example.com/callback",
+		"https://app.example.com/home",
+	}}
+	if err := b.waitForSilentLogin(sso, "app.example.com"); err != nil {
+		t.Errorf("expected the SSO login to settle, got %v", err)
+	}
+
+	prompt := &scriptedDriver{
+		urls:    []string{"https://app.example.com/", "https://login.microsoftonline.com/common/login"},
+		prompts: map[string]bool{"https://login.microsoftonline.com/common/login": true},
+	}
+	err := b.waitForSilentLogin(prompt, "app.example.com")
+	if !errors.Is(err, errCredentialPrompt) || !strings.Contains(err.Error(), "login.microsoftonline.com") {
+		t.Errorf("expected a credential prompt error, got %v", err)
+	}
+
+	stuck := &scriptedDriver{current: "https://login.microsoftonline.com/common/oauth2/authorize"}
+	if err := b.waitForSilentLogin(stuck, "app.example.com"); err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+}
+
+func TestLaunchArgs_Silent(t *testing.T) {
+	config := &BrowserConfig{UserDataDir: "/tmp/edge-debug", Silent: true}
+	args := strings.Join(config.launchArgs("--remote-debugging-pipe"), " ")
+	if !strings.HasSuffix(args, " --headless=new") {
+		t.Errorf("args = %q, want a headless launch", args)
+	}
+}
@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/go-rod/rod"
+	"github.com/go-rod/rod/lib/proto"
+)
+
+// NormalizeOrigin turns a host ("auth.contoso.com") or URL into the security
+// origin browsers key storage by, e.g. "https://auth.contoso.com"
+func NormalizeOrigin(origin string) (string, error) {
+	if !strings.Contains(origin, "://") {
+		origin = "https://" + origin
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return "", fmt.Errorf("invalid storage origin %q (use a host or https://host[:port])", origin)
+	}
+	return u.Scheme + "://" + strings.ToLower(u.Host), nil
+}
+
+// findOriginFrame returns the first frame in a frame tree whose document is
+// on origin, or nil
+func findOriginFrame(tree *proto.PageFrameTree, origin string) *proto.PageFrame {
+	if tree == nil || tree.Frame == nil {
+		return nil
+	}
+	if tree.Frame.SecurityOrigin == origin {
+		return tree.Frame
+	}
+	for _, child := range tree.ChildFrames {
+		if frame := findOriginFrame(child, origin); frame != nil {
+			return frame
+		}
+	}
+	return nil
+}
+
+// originFrame is an open tab or frame on a security origin, whose web
+// storage is read rather than that of the driver's page
+type originFrame struct {
+	page   *rod.Page
+	frame  *proto.PageFrame
+	origin string
+}
+
+// findOriginStorage finds the first open tab or frame on origin, looking at
+// preferred first. Cross-site iframes run as their own targets, so those are
+// searched after the tabs.
+func findOriginStorage(browser *rod.Browser, preferred *rod.Page, origin string) (*originFrame, error) {
+	candidates, err := storageCandidates(browser, preferred)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, page := range candidates {
+		tree, err := proto.PageGetFrameTree{}.Call(page)
+		if err != nil {
+			continue
+		}
+		if frame := findOriginFrame(tree.FrameTree, origin); frame != nil {
+			return &originFrame{page: page, frame: frame, origin: origin}, nil
+		}
+	}
+	return nil, fmt.Errorf("no open tab or frame is on %s; open the app so it loads that origin", origin)
+}
+
+// storageCandidates lists preferred, the browser's tabs and its
+// out-of-process iframes, without duplicates
+func storageCandidates(browser *rod.Browser, preferred *rod.Page) ([]*rod.Page, error) {
+	targets, err := proto.TargetGetTargets{}.Call(browser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list targets: %w", err)
+	}
+
+	var candidates []*rod.Page
+	seen := map[proto.TargetTargetID]bool{}
+	if preferred != nil {
+		candidates = append(candidates, preferred)
+		seen[preferred.TargetID] = true
+	}
+
+	// rod has no constant for "iframe" targets
+	for _, kind := range []proto.TargetTargetInfoType{proto.TargetTargetInfoTypePage, "iframe"} {
+		for _, t := range targets.TargetInfos {
+			if t.Type != kind || seen[t.TargetID] {
+				continue
+			}
+			page, err := browser.PageFromTarget(t.TargetID)
+			if err != nil {
+				continue
+			}
+			candidates = append(candidates, page)
+			seen[t.TargetID] = true
+		}
+	}
+	return candidates, nil
+}
+
+// LocalStorage reads the frame's localStorage
+func (f *originFrame) LocalStorage() (map[string]string, error) {
+	return f.storage(true, localStorageJS)
+}
+
+// SessionStorage reads the frame's sessionStorage, where MSAL.js keeps its
+// token cache by default
+func (f *originFrame) SessionStorage() (map[string]string, error) {
+	return f.storage(false, sessionStorageJS)
+}
+
+// FirebaseIndexedDB reads the records of Firebase Auth's
+// firebaseLocalStorageDb in the frame
+func (f *originFrame) FirebaseIndexedDB() (map[string]string, error) {
+	jsonStr, err := f.eval(firebaseIndexedDBJS)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IndexedDB of %s: %w", f.origin, err)
+	}
+	return ParseLocalStorageJSON(jsonStr)
+}
+
+// storage reads the frame's localStorage or sessionStorage through the
+// DOMStorage domain. If the browser does not serve it there, the storage is
+// read by evaluating js in an isolated world of the frame, which leaves the
+// page's own scripts undisturbed.
+func (f *originFrame) storage(local bool, js string) (map[string]string, error) {
+	kind := "sessionStorage"
+	if local {
+		kind = "localStorage"
+	}
+
+	if err := (proto.DOMStorageEnable{}).Call(f.page); err == nil {
+		// Newer Chromium keys storage by storage key ("origin/") instead of origin
+		for _, id := range []*proto.DOMStorageStorageID{
+			{SecurityOrigin: f.origin, IsLocalStorage: local},
+			{StorageKey: proto.DOMStorageSerializedStorageKey(f.origin + "/"), IsLocalStorage: local},
+		} {
+			items, err := proto.DOMStorageGetDOMStorageItems{StorageID: id}.Call(f.page)
+			if err != nil {
+				continue
+			}
+			entries := map[string]string{}
+			for _, item := range items.Entries {
+				if len(item) == 2 {
+					entries[item[0]] = item[1]
+				}
+			}
+			return entries, nil
+		}
+	}
+
+	jsonStr, err := f.eval(js)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s of %s: %w", kind, f.origin, err)
+	}
+	return ParseLocalStorageJSON(jsonStr)
+}
+
+// eval runs a JS function expression in an isolated world of the frame and
+// returns its string result, awaiting it when it is a promise
+func (f *originFrame) eval(js string) (string, error) {
+	world, err := proto.PageCreateIsolatedWorld{FrameID: f.frame.ID, WorldName: "fetch"}.Call(f.page)
+	if err != nil {
+		return "", fmt.Errorf("failed to enter frame: %w", err)
+	}
+	result, err := proto.RuntimeEvaluate{
+		Expression:    "(" + js + ")()",
+		ContextID:     world.ExecutionContextID,
+		ReturnByValue: true,
+		AwaitPromise:  true,
+	}.Call(f.page)
+	if err != nil {
+		return "", err
+	}
+	if result.ExceptionDetails != nil {
+		return "", fmt.Errorf("%s", result.ExceptionDetails.Text)
+	}
+	return result.Result.Value.Str(), nil
+}
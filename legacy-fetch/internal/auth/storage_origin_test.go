@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/go-rod/rod/lib/proto"
+)
+
+func TestNormalizeOrigin(t *testing.T) {
+	tests := map[string]string{
+		"auth.contoso.com":                   "https://auth.contoso.com",
+		"https://Auth.Contoso.com/login?x=1": "https://auth.contoso.com",
+		"http://localhost:3000/":             "http://localhost:3000",
+	}
+	for in, want := range tests {
+		if got, err := NormalizeOrigin(in); err != nil || got != want {
+			t.Errorf("NormalizeOrigin(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+
+	for _, bad := range []string{"ftp://files.contoso.com", "https://"} {
+		if _, err := NormalizeOrigin(bad); err == nil {
+			t.Errorf("expected NormalizeOrigin(%q) to fail", bad)
+		}
+	}
+}
+
+func TestFindOriginFrame(t *testing.T) {
+	frame := func(id, origin string, children ...*proto.PageFrameTree) *proto.PageFrameTree {
+		return &proto.PageFrameTree{
+			Frame:       &proto.PageFrame{ID: proto.PageFrameID(id), SecurityOrigin: origin},
+			ChildFrames: children,
+		}
+	}
+	tree := frame("main", "https://app.contoso.com",
+		frame("ads", "https://ads.example.net"),
+		frame("widget", "https://widget.contoso.com",
+			frame("silent-renew", "https://auth.contoso.com")))
+
+	if got := findOriginFrame(tree, "https://auth.contoso.com"); got == nil || got.ID != "silent-renew" {
+		t.Errorf("expected the nested auth frame, got %+v", got)
+	}
+	if got := findOriginFrame(tree, "https://app.contoso.com"); got == nil || got.ID != "main" {
+		t.Errorf("expected the main frame, got %+v", got)
+	}
+	if got := findOriginFrame(tree, "https://other.contoso.com"); got != nil {
+		t.Errorf("expected no frame, got %+v", got)
+	}
+}
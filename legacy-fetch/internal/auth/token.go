@@ -4,40 +4,259 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
 )
 
+// auth0CachePrefix starts the localStorage keys of the Auth0 SPA SDK cache,
+// which are "@@auth0spajs@@::<client_id>::<audience>::<scope>"
+const auth0CachePrefix = "@@auth0spajs@@"
+
 // auth0CacheEntry represents the Auth0 SPA SDK localStorage cache format
 type auth0CacheEntry struct {
 	Body struct {
-		AccessToken string `json:"access_token"`
-		ExpiresIn   int    `json:"expires_in"`
-		TokenType   string `json:"token_type"`
+		ClientID     string `json:"client_id"`
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+		TokenType    string `json:"token_type"`
+		Audience     string `json:"audience"`
+		Scope        string `json:"scope"`
+		DecodedToken struct {
+			Claims struct {
+				Issuer string `json:"iss"`
+			} `json:"claims"`
+		} `json:"decodedToken"`
 	} `json:"body"`
 	ExpiresAt int64 `json:"expiresAt"`
 }
 
+// auth0UserEntry is the SDK's cached ID token ("...::@@user@@" key)
+type auth0UserEntry struct {
+	DecodedToken struct {
+		Claims struct {
+			Issuer string `json:"iss"`
+		} `json:"claims"`
+	} `json:"decodedToken"`
+}
+
+// Auth0Tokens is the token set the Auth0 SPA SDK caches for one client,
+// audience and scope. With rotating refresh tokens enabled it includes a
+// refresh token, which RefreshAuth0Tokens exchanges for new tokens.
+type Auth0Tokens struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ClientID     string    `json:"client_id"`
+	Audience     string    `json:"audience,omitempty"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	TokenURL     string    `json:"token_url,omitempty"` // The tenant's /oauth/token endpoint
+}
+
+// Expired reports whether the access token expires within margin
+func (t *Auth0Tokens) Expired(margin time.Duration) bool {
+	return !t.ExpiresAt.IsZero() && time.Now().Add(margin).After(t.ExpiresAt)
+}
+
+// CanRefresh reports whether the tokens can be refreshed without a browser
+func (t *Auth0Tokens) CanRefresh() bool {
+	return t.RefreshToken != "" && t.ClientID != "" && t.TokenURL != ""
+}
+
 // ParseAuth0Token finds and extracts an access_token from Auth0 SPA SDK
 // localStorage entries. Keys are prefixed with @@auth0spajs@@.
 func ParseAuth0Token(localStorage map[string]string) (string, error) {
+	tokens, err := ParseAuth0Tokens(localStorage)
+	if err != nil {
+		return "", err
+	}
+	return tokens.AccessToken, nil
+}
+
+// Auth0Selector picks one of the token sets an app caches when it calls
+// several APIs. Empty fields match any token set.
+type Auth0Selector struct {
+	Audience string
+	Scope    string // Space-separated scopes the token must include
+}
+
+// Matches reports whether a token set was issued for the selected audience
+// and includes every selected scope
+func (s Auth0Selector) Matches(t *Auth0Tokens) bool {
+	if s.Audience != "" && strings.TrimSuffix(s.Audience, "/") != strings.TrimSuffix(t.Audience, "/") {
+		return false
+	}
+	return hasScopes(t.Scope, s.Scope)
+}
+
+// hasScopes reports whether the space-separated granted scopes include
+// every wanted scope
+func hasScopes(granted, wanted string) bool {
+	scopes := strings.Fields(granted)
+	for _, want := range strings.Fields(wanted) {
+		found := false
+		for _, scope := range scopes {
+			if scope == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// String describes the selector for error messages
+func (s Auth0Selector) String() string {
+	var parts []string
+	if s.Audience != "" {
+		parts = append(parts, fmt.Sprintf("audience %q", s.Audience))
+	}
+	if s.Scope != "" {
+		parts = append(parts, fmt.Sprintf("scope %q", s.Scope))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// ParseAuth0Tokens extracts the full token set from Auth0 SPA SDK
+// localStorage entries: the access token and, when present, the refresh
+// token with the client, audience and scope it was issued for. The token
+// endpoint is derived from the tenant that issued the tokens.
+func ParseAuth0Tokens(localStorage map[string]string) (*Auth0Tokens, error) {
+	return SelectAuth0Tokens(localStorage, Auth0Selector{})
+}
+
+// SelectAuth0Tokens returns the cached token set matching selector. Live
+// tokens are preferred over expired ones; among several, the first by
+// audience and scope is taken so the choice is stable. When every match has
+// expired, the one expiring last is returned for its refresh token.
+func SelectAuth0Tokens(localStorage map[string]string, selector Auth0Selector) (*Auth0Tokens, error) {
+	all, problems := ListAuth0Tokens(localStorage)
+	if len(all) == 0 {
+		if len(problems) > 0 {
+			return nil, fmt.Errorf("no usable Auth0 token in localStorage: %w", problems[0])
+		}
+		return nil, fmt.Errorf("no Auth0 token found in localStorage")
+	}
+
+	var expired *Auth0Tokens
+	for _, tokens := range all {
+		if !selector.Matches(tokens) {
+			continue
+		}
+		if !tokens.Expired(0) {
+			return tokens, nil
+		}
+		if expired == nil || tokens.ExpiresAt.After(expired.ExpiresAt) {
+			expired = tokens
+		}
+	}
+	if expired != nil {
+		return expired, nil
+	}
+
+	var available []string
+	for _, tokens := range all {
+		available = append(available, fmt.Sprintf("%s [%s]", tokens.Audience, tokens.Scope))
+	}
+	return nil, fmt.Errorf("no Auth0 token for %s (available: %s)", selector, strings.Join(available, ", "))
+}
+
+// ListAuth0Tokens parses every Auth0 SPA SDK cache entry in localStorage,
+// sorted by audience and scope. Entries that cannot be parsed or hold no
+// access token are skipped and reported as problems.
+func ListAuth0Tokens(localStorage map[string]string) ([]*Auth0Tokens, []error) {
+	issuer := ""
 	for key, value := range localStorage {
-		if !strings.HasPrefix(key, "@@auth0spajs@@") {
+		if strings.HasPrefix(key, auth0CachePrefix) && strings.HasSuffix(key, "::@@user@@") {
+			var user auth0UserEntry
+			if json.Unmarshal([]byte(value), &user) == nil {
+				issuer = user.DecodedToken.Claims.Issuer
+			}
+		}
+	}
+
+	var all []*Auth0Tokens
+	var problems []error
+	for key, value := range localStorage {
+		if !strings.HasPrefix(key, auth0CachePrefix) || strings.HasSuffix(key, "::@@user@@") {
 			continue
 		}
+		tokens, err := parseAuth0Entry(key, value, issuer)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		all = append(all, tokens)
+	}
 
-		var entry auth0CacheEntry
-		if err := json.Unmarshal([]byte(value), &entry); err != nil {
-			return "", fmt.Errorf("failed to parse Auth0 cache entry: %w", err)
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].Audience != all[j].Audience {
+			return all[i].Audience < all[j].Audience
 		}
+		return all[i].Scope < all[j].Scope
+	})
+	return all, problems
+}
 
-		if entry.Body.AccessToken == "" {
-			return "", fmt.Errorf("Auth0 cache entry has no access_token")
+// parseAuth0Entry parses one SDK cache entry. issuer is the tenant from the
+// cached ID token, used when the access token does not name one.
+func parseAuth0Entry(key, value, issuer string) (*Auth0Tokens, error) {
+	var entry auth0CacheEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, fmt.Errorf("failed to parse Auth0 cache entry %q: %w", key, err)
+	}
+
+	if entry.Body.AccessToken == "" {
+		return nil, fmt.Errorf("Auth0 cache entry %q has no access_token", key)
+	}
+
+	tokens := &Auth0Tokens{
+		AccessToken:  entry.Body.AccessToken,
+		RefreshToken: entry.Body.RefreshToken,
+		ClientID:     entry.Body.ClientID,
+		Audience:     entry.Body.Audience,
+		Scope:        entry.Body.Scope,
+	}
+
+	// The key carries the same fields for SDK versions that omit them from the body
+	if parts := strings.SplitN(key, "::", 4); len(parts) == 4 {
+		if tokens.ClientID == "" {
+			tokens.ClientID = parts[1]
+		}
+		if tokens.Audience == "" {
+			tokens.Audience = parts[2]
+		}
+		if tokens.Scope == "" {
+			tokens.Scope = parts[3]
 		}
+	}
+
+	if entry.ExpiresAt > 0 {
+		tokens.ExpiresAt = time.Unix(entry.ExpiresAt, 0)
+	}
 
-		return entry.Body.AccessToken, nil
+	// An API access token is itself a JWT naming the tenant; the ID token is the fallback
+	tokenIssuer := entry.Body.DecodedToken.Claims.Issuer
+	if jwt, err := DecodeJWT(tokens.AccessToken); err == nil {
+		if iss := jwt.StringClaim("iss"); iss != "" {
+			tokenIssuer = iss
+		}
+		if exp, ok := jwt.TimeClaim("exp"); ok && tokens.ExpiresAt.IsZero() {
+			tokens.ExpiresAt = exp
+		}
+	}
+	if tokenIssuer == "" {
+		tokenIssuer = issuer
+	}
+	if tokenIssuer != "" {
+		tokens.TokenURL = strings.TrimSuffix(tokenIssuer, "/") + "/oauth/token"
 	}
 
-	return "", fmt.Errorf("no Auth0 token found in localStorage")
+	return tokens, nil
 }
 
 // FormatTokenOutput formats a JWT and cookies as KEY=value lines for stdout.
@@ -49,11 +268,7 @@ func FormatTokenOutput(jwt string, cookies []*http.Cookie) string {
 	}
 
 	if len(cookies) > 0 {
-		var parts []string
-		for _, c := range cookies {
-			parts = append(parts, c.Name+"="+c.Value)
-		}
-		lines = append(lines, "COOKIE="+strings.Join(parts, "; "))
+		lines = append(lines, "COOKIE="+cookieHeader(cookies))
 	}
 
 	return strings.Join(lines, "\n")
@@ -0,0 +1,386 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoToken is returned by a TokenExtractor when the capture holds none of
+// its provider's tokens, so the next extractor should be tried
+var ErrNoToken = errors.New("no token found")
+
+// TokenQuery selects among the tokens of a capture. Empty fields match any
+// token; each provider maps them onto its own cache layout.
+type TokenQuery struct {
+	Audience string // API audience (Auth0, JWT aud) or resource (MSAL)
+	Scope    string // Space-separated scopes the token must include
+	Tenant   string // Azure AD tenant (MSAL)
+}
+
+// ExtractedToken is an access token found in a capture
+type ExtractedToken struct {
+	Provider  string
+	Token     string
+	Source    string // Where it was found, e.g. "localStorage" or "Authorization header"
+	Audience  string
+	Scope     string
+	Tenant    string
+	Subject   string    // The signed-in user, when known
+	ExpiresAt time.Time // Zero when unknown
+
+	// Auth0 is the full Auth0 token set, with the refresh token the session
+	// keeps. Only set by the auth0 extractor.
+	Auth0 *Auth0Tokens
+	// Firebase is the signed-in Firebase user, likewise kept with the
+	// session. Only set by the firebase extractor.
+	Firebase *FirebaseUser
+}
+
+// Expired reports whether the token expires within margin
+func (t *ExtractedToken) Expired(margin time.Duration) bool {
+	return !t.ExpiresAt.IsZero() && time.Now().Add(margin).After(t.ExpiresAt)
+}
+
+// TokenExtractor finds the access token of one identity provider in a
+// captured login: its cookies, storage and observed request headers
+type TokenExtractor interface {
+	// Name is the --provider value that selects the extractor
+	Name() string
+	// Extract returns the token matching query. It returns ErrNoToken when
+	// the capture holds none of the provider's tokens, and another error
+	// when it does but none match.
+	Extract(result *AuthResult, query TokenQuery) (*ExtractedToken, error)
+	// List returns every token of the provider in the capture, and the
+	// entries that could not be read
+	List(result *AuthResult) ([]*ExtractedToken, []error)
+}
+
+// TokenExtractors holds the extractors the token command can use, in the
+// order they are tried
+type TokenExtractors struct {
+	extractors []TokenExtractor
+}
+
+// NewTokenExtractors creates a registry of the built-in extractors. The
+// generic JWT heuristic comes last, after every provider that knows its
+// cache layout.
+func NewTokenExtractors() *TokenExtractors {
+	r := &TokenExtractors{}
+	r.Register(auth0Extractor{})
+	r.Register(msalExtractor{})
+	r.Register(cognitoExtractor{})
+	r.Register(firebaseExtractor{})
+	r.Register(jwtExtractor{})
+	return r
+}
+
+// Register adds an extractor, replacing any existing one with the same name
+func (r *TokenExtractors) Register(extractor TokenExtractor) {
+	for i, e := range r.extractors {
+		if e.Name() == extractor.Name() {
+			r.extractors[i] = extractor
+			return
+		}
+	}
+	r.extractors = append(r.extractors, extractor)
+}
+
+// Lookup returns the extractor with the given name
+func (r *TokenExtractors) Lookup(name string) (TokenExtractor, error) {
+	for _, e := range r.extractors {
+		if e.Name() == name {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown token provider %q (available: %s)", name, strings.Join(r.Names(), ", "))
+}
+
+// Names returns the registered extractor names in the order they are tried
+func (r *TokenExtractors) Names() []string {
+	names := make([]string, len(r.extractors))
+	for i, e := range r.extractors {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+// Extract finds the token matching query with the named extractor, or when
+// provider is empty with the first extractor whose provider the capture
+// holds tokens of
+func (r *TokenExtractors) Extract(result *AuthResult, query TokenQuery, provider string) (*ExtractedToken, error) {
+	extractors := r.extractors
+	if provider != "" {
+		e, err := r.Lookup(provider)
+		if err != nil {
+			return nil, err
+		}
+		extractors = []TokenExtractor{e}
+	}
+
+	for _, e := range extractors {
+		token, err := e.Extract(result, query)
+		if errors.Is(err, ErrNoToken) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Name(), err)
+		}
+		return token, nil
+	}
+	if provider != "" {
+		return nil, fmt.Errorf("%s: %w", provider, ErrNoToken)
+	}
+	return nil, ErrNoToken
+}
+
+// List returns the tokens of every extractor, or of the named one
+func (r *TokenExtractors) List(result *AuthResult, provider string) ([]*ExtractedToken, []error, error) {
+	extractors := r.extractors
+	if provider != "" {
+		e, err := r.Lookup(provider)
+		if err != nil {
+			return nil, nil, err
+		}
+		extractors = []TokenExtractor{e}
+	}
+
+	var all []*ExtractedToken
+	var problems []error
+	for _, e := range extractors {
+		tokens, errs := e.List(result)
+		all = append(all, tokens...)
+		for _, err := range errs {
+			problems = append(problems, fmt.Errorf("%s: %w", e.Name(), err))
+		}
+	}
+	return all, problems, nil
+}
+
+// auth0Extractor reads the Auth0 SPA SDK cache in localStorage
+type auth0Extractor struct{}
+
+func (auth0Extractor) Name() string { return "auth0" }
+
+func (auth0Extractor) Extract(result *AuthResult, query TokenQuery) (*ExtractedToken, error) {
+	if query.Tenant != "" {
+		return nil, ErrNoToken // Auth0 tokens carry no Azure AD tenant
+	}
+	if all, problems := ListAuth0Tokens(result.LocalStorage); len(all) == 0 && len(problems) == 0 {
+		return nil, ErrNoToken
+	}
+	tokens, err := SelectAuth0Tokens(result.LocalStorage, Auth0Selector{Audience: query.Audience, Scope: query.Scope})
+	if err != nil {
+		return nil, err
+	}
+	return auth0Token(tokens), nil
+}
+
+func (auth0Extractor) List(result *AuthResult) ([]*ExtractedToken, []error) {
+	all, problems := ListAuth0Tokens(result.LocalStorage)
+	tokens := make([]*ExtractedToken, len(all))
+	for i, t := range all {
+		tokens[i] = auth0Token(t)
+	}
+	return tokens, problems
+}
+
+// auth0Token describes an Auth0 token set as an ExtractedToken
+func auth0Token(t *Auth0Tokens) *ExtractedToken {
+	return &ExtractedToken{
+		Provider:  "auth0",
+		Token:     t.AccessToken,
+		Source:    "localStorage",
+		Audience:  t.Audience,
+		Scope:     t.Scope,
+		ExpiresAt: t.ExpiresAt,
+		Auth0:     t,
+	}
+}
+
+// msalExtractor reads the MSAL.js cache in sessionStorage or localStorage
+type msalExtractor struct{}
+
+func (msalExtractor) Name() string { return "msal" }
+
+func (msalExtractor) Extract(result *AuthResult, query TokenQuery) (*ExtractedToken, error) {
+	cache, _ := ParseMSALCache(result.SessionStorage, result.LocalStorage)
+	if len(cache.AccessTokens()) == 0 {
+		return nil, ErrNoToken
+	}
+	token, err := SelectMSALToken(cache, MSALSelector{Resource: query.Audience, Scope: query.Scope, Tenant: query.Tenant})
+	if err != nil {
+		return nil, err
+	}
+	return msalToken(cache, token), nil
+}
+
+func (msalExtractor) List(result *AuthResult) ([]*ExtractedToken, []error) {
+	cache, problems := ParseMSALCache(result.SessionStorage, result.LocalStorage)
+	var tokens []*ExtractedToken
+	for _, t := range cache.AccessTokens() {
+		tokens = append(tokens, msalToken(cache, t))
+	}
+	return tokens, problems
+}
+
+// msalToken describes an MSAL access token as an ExtractedToken, naming the
+// account it was issued to
+func msalToken(cache *MSALCache, c *MSALCredential) *ExtractedToken {
+	token := &ExtractedToken{
+		Provider:  "msal",
+		Token:     c.Secret,
+		Source:    "MSAL cache",
+		Scope:     c.Scopes,
+		Tenant:    c.TenantID,
+		ExpiresAt: c.ExpiresAt,
+	}
+	for _, account := range cache.Accounts {
+		if account.HomeAccountID == c.HomeAccountID {
+			token.Subject = account.Username
+		}
+	}
+	return token
+}
+
+// jwtPattern matches a compact JWT: base64url header (which starts with
+// "eyJ", i.e. `{"`), claims and signature
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+
+// jwtExtractor is the fallback heuristic for providers without an extractor:
+// it finds JWT-shaped values in the request headers the page sent, its
+// storage and its cookies
+type jwtExtractor struct{}
+
+func (jwtExtractor) Name() string { return "jwt" }
+
+// Extract prefers a token the page actually sent in a header, then a live
+// token over an expired one. A token without an exp claim counts as live.
+func (e jwtExtractor) Extract(result *AuthResult, query TokenQuery) (*ExtractedToken, error) {
+	all, _ := e.List(result)
+	if len(all) == 0 {
+		return nil, ErrNoToken
+	}
+
+	var expired *ExtractedToken
+	for _, token := range all {
+		if !query.matchesJWT(token) {
+			continue
+		}
+		if !token.Expired(0) {
+			return token, nil
+		}
+		if expired == nil {
+			expired = token
+		}
+	}
+	if expired != nil {
+		return expired, nil
+	}
+	return nil, fmt.Errorf("none of the %d JWTs found matches the audience and scope", len(all))
+}
+
+// List returns every distinct JWT in the capture, headers first, then
+// sessionStorage, localStorage and cookies
+func (jwtExtractor) List(result *AuthResult) ([]*ExtractedToken, []error) {
+	var all []*ExtractedToken
+	seen := map[string]bool{}
+	add := func(source, value string) {
+		for _, raw := range jwtPattern.FindAllString(value, -1) {
+			if seen[raw] {
+				continue
+			}
+			jwt, err := DecodeJWT(raw)
+			if err != nil || jwt.Header["alg"] == nil {
+				continue
+			}
+			seen[raw] = true
+			all = append(all, jwtToken(jwt, source))
+		}
+	}
+
+	for _, name := range sortedKeys(result.Headers) {
+		for _, value := range result.Headers[name] {
+			add(name+" header", value)
+		}
+	}
+	for _, storage := range []struct {
+		name    string
+		entries map[string]string
+	}{
+		{"sessionStorage", result.SessionStorage},
+		{"localStorage", result.LocalStorage},
+	} {
+		for _, key := range sortedKeys(storage.entries) {
+			add(storage.name+" "+key, storage.entries[key])
+		}
+	}
+	for _, cookie := range result.Cookies {
+		add("cookie "+cookie.Name, cookie.Value)
+	}
+	return all, nil
+}
+
+// jwtToken describes a decoded JWT as an ExtractedToken
+func jwtToken(jwt *JWT, source string) *ExtractedToken {
+	token := &ExtractedToken{
+		Provider: "jwt",
+		Token:    jwt.Raw,
+		Source:   source,
+		Audience: strings.Join(jwt.Audiences(), " "),
+		Scope:    jwt.StringClaim("scope"),
+		Tenant:   jwt.StringClaim("tid"),
+		Subject:  jwt.StringClaim("sub"),
+	}
+	if token.Scope == "" {
+		token.Scope = jwt.StringClaim("scp")
+	}
+	if exp, ok := jwt.TimeClaim("exp"); ok {
+		token.ExpiresAt = exp
+	}
+	return token
+}
+
+// matchesJWT reports whether a token found by the JWT heuristic has the
+// queried audience, scopes and tenant
+func (q TokenQuery) matchesJWT(token *ExtractedToken) bool {
+	if q.Audience != "" {
+		found := false
+		for _, aud := range strings.Fields(token.Audience) {
+			if strings.TrimSuffix(aud, "/") == strings.TrimSuffix(q.Audience, "/") {
+				found = true
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Tenant != "" && !strings.EqualFold(q.Tenant, token.Tenant) {
+		return false
+	}
+	return hasScopes(token.Scope, q.Scope)
+}
+
+// sortedKeys returns a map's keys in order, so extraction is deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonString unquotes a storage value that holds a JSON string, as some
+// SDKs store tokens; other values are returned unchanged
+func jsonString(value string) string {
+	var s string
+	if strings.HasPrefix(value, `"`) && json.Unmarshal([]byte(value), &s) == nil {
+		return s
+	}
+	return value
+}
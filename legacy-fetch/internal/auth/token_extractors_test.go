@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenExtractors_Registry(t *testing.T) {
+	r := NewTokenExtractors()
+	if got := strings.Join(r.Names(), ","); got != "auth0,msal,cognito,firebase,jwt" {
+		t.Errorf("Names() = %s", got)
+	}
+	if _, err := r.Lookup("okta"); err == nil || !strings.Contains(err.Error(), "auth0, msal, cognito, firebase, jwt") {
+		t.Errorf("expected an unknown provider error listing the providers, got %v", err)
+	}
+
+	_, err := r.Extract(&AuthResult{LocalStorage: map[string]string{"theme": "dark"}}, TokenQuery{}, "")
+	if !errors.Is(err, ErrNoToken) {
+		t.Errorf("expected ErrNoToken for a capture without tokens, got %v", err)
+	}
+}
+
+func TestTokenExtractors_TriesInOrder(t *testing.T) {
+	live := time.Now().Add(time.Hour).Unix()
+	result := &AuthResult{
+		LocalStorage: map[string]string{
+			"@@auth0spajs@@::client::https://api.example.com::openid": auth0Entry("auth0-token", live),
+		},
+		Headers: http.Header{"Authorization": {"Bearer " + testJWT(`{"aud":"https://other.example.com"}`)}},
+	}
+
+	token, err := NewTokenExtractors().Extract(result, TokenQuery{}, "")
+	if err != nil || token.Provider != "auth0" || token.Token != "auth0-token" || token.Auth0 == nil {
+		t.Fatalf("expected the Auth0 token first, got %+v, %v", token, err)
+	}
+
+	token, err = NewTokenExtractors().Extract(result, TokenQuery{}, "jwt")
+	if err != nil || token.Provider != "jwt" || token.Source != "Authorization header" {
+		t.Fatalf("expected the header JWT with --provider jwt, got %+v, %v", token, err)
+	}
+
+	// Auth0 holds tokens but none for the audience: that is an error, not a fallback
+	if _, err := NewTokenExtractors().Extract(result, TokenQuery{Audience: "https://other.example.com"}, ""); err == nil || !strings.HasPrefix(err.Error(), "auth0: ") {
+		t.Errorf("expected the Auth0 selection error, got %v", err)
+	}
+}
+
+func TestCognitoExtractor(t *testing.T) {
+	accessToken := testJWT(`{"client_id":"client1","scope":"aws.cognito.signin.user.admin orders/read","exp":4102444800}`)
+	result := &AuthResult{LocalStorage: map[string]string{
+		"CognitoIdentityServiceProvider.client1.LastAuthUser":       "ada",
+		"CognitoIdentityServiceProvider.client1.ada.accessToken":    accessToken,
+		"CognitoIdentityServiceProvider.client1.ada.idToken":        testJWT(`{"token_use":"id"}`),
+		"CognitoIdentityServiceProvider.client1.bob.accessToken":    "stale-user-token",
+		"CognitoIdentityServiceProvider.client2.LastAuthUser":       "grace",
+		"CognitoIdentityServiceProvider.client2.grace.refreshToken": "refresh",
+		"CognitoIdentityServiceProvider.client2.grace.clockDrift":   "0",
+		"amplify-signin-with-hostedUI":                              "false",
+	}}
+
+	tokens, problems := cognitoExtractor{}.List(result)
+	if len(tokens) != 1 || tokens[0].Token != accessToken || tokens[0].Subject != "ada" || tokens[0].Audience != "client1" {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	if len(problems) != 1 {
+		t.Errorf("expected client2's missing access token to be reported, got %v", problems)
+	}
+
+	token, err := NewTokenExtractors().Extract(result, TokenQuery{Scope: "orders/read"}, "")
+	if err != nil || token.Provider != "cognito" || token.ExpiresAt.Year() != 2100 {
+		t.Errorf("expected the Cognito token, got %+v, %v", token, err)
+	}
+	if _, err := (cognitoExtractor{}).Extract(result, TokenQuery{Audience: "client3"}); err == nil {
+		t.Error("expected an error for an unknown app client")
+	}
+}
+
+func TestJWTExtractor(t *testing.T) {
+	expired := testJWT(`{"aud":"api","exp":1700000000}`)
+	live := testJWT(`{"aud":["api","other"],"scp":"read write","exp":4102444800}`)
+	result := &AuthResult{
+		LocalStorage:   map[string]string{"session": `{"token":"` + expired + `"}`, "notajwt": "eyJhbGciOi.x.y"},
+		SessionStorage: map[string]string{"token": `"` + live + `"`},
+		Cookies:        []*http.Cookie{{Name: "jwt", Value: expired}},
+	}
+
+	tokens, _ := jwtExtractor{}.List(result)
+	if len(tokens) != 2 || tokens[0].Source != "sessionStorage token" || tokens[1].Source != "localStorage session" {
+		t.Errorf("expected each distinct JWT once, sessionStorage first, got %+v", tokens)
+	}
+
+	token, err := jwtExtractor{}.Extract(result, TokenQuery{Audience: "api", Scope: "write"})
+	if err != nil || token.Token != live {
+		t.Errorf("expected the live token, got %+v, %v", token, err)
+	}
+	if _, err := (jwtExtractor{}).Extract(result, TokenQuery{Audience: "billing"}); err == nil {
+		t.Error("expected an error when no JWT has the audience")
+	}
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TokenFormats are the output formats of fetch token
+var TokenFormats = []string{"text", "json", "env", "dotenv", "header", "curl", "netscape"}
+
+// FormatToken renders a JWT and cookies in one of TokenFormats:
+//
+//	text      JWT= and COOKIE= lines (FormatTokenOutput)
+//	json      an object with the JWT, the Cookie header and each cookie
+//	env       export statements for a POSIX shell
+//	dotenv    KEY=value lines for .env files
+//	header    an Authorization header line
+//	curl      a curl --config snippet with the Authorization and Cookie headers
+//	netscape  cookies.txt lines, as curl -b and wget --load-cookies read
+func FormatToken(format, jwt string, cookies []*http.Cookie) (string, error) {
+	switch format {
+	case "", "text":
+		return FormatTokenOutput(jwt, cookies), nil
+
+	case "json":
+		return formatTokenJSON(jwt, cookies)
+
+	case "env":
+		var lines []string
+		if jwt != "" {
+			lines = append(lines, "export FETCH_JWT="+shellQuote(jwt))
+		}
+		if len(cookies) > 0 {
+			lines = append(lines, "export FETCH_COOKIE="+shellQuote(cookieHeader(cookies)))
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "dotenv":
+		var lines []string
+		if jwt != "" {
+			lines = append(lines, "FETCH_JWT="+dotenvQuote(jwt))
+		}
+		if len(cookies) > 0 {
+			lines = append(lines, "FETCH_COOKIE="+dotenvQuote(cookieHeader(cookies)))
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "header":
+		if jwt == "" {
+			return "", fmt.Errorf("no JWT was captured to put in an Authorization header")
+		}
+		return "Authorization: Bearer " + jwt, nil
+
+	case "curl":
+		var lines []string
+		if jwt != "" {
+			lines = append(lines, "header = "+curlQuote("Authorization: Bearer "+jwt))
+		}
+		if len(cookies) > 0 {
+			lines = append(lines, "header = "+curlQuote("Cookie: "+cookieHeader(cookies)))
+		}
+		return strings.Join(lines, "\n"), nil
+
+	case "netscape":
+		return formatNetscapeCookies(cookies), nil
+	}
+	return "", fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(TokenFormats, ", "))
+}
+
+// cookieHeader joins cookies into a Cookie header value
+func cookieHeader(cookies []*http.Cookie) string {
+	parts := make([]string, len(cookies))
+	for i, c := range cookies {
+		parts[i] = c.Name + "=" + c.Value
+	}
+	return strings.Join(parts, "; ")
+}
+
+// tokenCookieJSON is a cookie in the json format
+type tokenCookieJSON struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Domain   string `json:"domain,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Expires  int64  `json:"expires,omitempty"` // Unix seconds; 0 for session cookies
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"http_only"`
+}
+
+// formatTokenJSON renders the json format
+func formatTokenJSON(jwt string, cookies []*http.Cookie) (string, error) {
+	out := struct {
+		JWT          string            `json:"jwt,omitempty"`
+		CookieHeader string            `json:"cookie_header,omitempty"`
+		Cookies      []tokenCookieJSON `json:"cookies"`
+	}{JWT: jwt, CookieHeader: cookieHeader(cookies), Cookies: []tokenCookieJSON{}}
+
+	for _, c := range cookies {
+		cookie := tokenCookieJSON{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, Secure: c.Secure, HTTPOnly: c.HttpOnly}
+		if c.Expires.Unix() > 0 {
+			cookie.Expires = c.Expires.Unix()
+		}
+		out.Cookies = append(out.Cookies, cookie)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token output: %w", err)
+	}
+	return string(data), nil
+}
+
+// formatNetscapeCookies renders cookies as cookies.txt lines. HttpOnly
+// cookies get curl's "#HttpOnly_" domain prefix.
+func formatNetscapeCookies(cookies []*http.Cookie) string {
+	lines := []string{"# Netscape HTTP Cookie File"}
+	for _, c := range cookies {
+		domain := c.Domain
+		includeSubdomains := "FALSE"
+		if strings.HasPrefix(domain, ".") {
+			includeSubdomains = "TRUE"
+		}
+		if c.HttpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+		path := c.Path
+		if path == "" {
+			path = "/"
+		}
+		secure := "FALSE"
+		if c.Secure {
+			secure = "TRUE"
+		}
+		var expires int64
+		if c.Expires.Unix() > 0 {
+			expires = c.Expires.Unix()
+		}
+		lines = append(lines, fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s", domain, includeSubdomains, path, secure, expires, c.Name, c.Value))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dotenvQuote double-quotes a .env value when it holds characters dotenv
+// parsers would otherwise split or interpret
+func dotenvQuote(value string) string {
+	if !strings.ContainsAny(value, " \t\n\"'\\#$;=`") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`).Replace(value) + `"`
+}
+
+// curlQuote quotes a curl config parameter, in which \ and " are escaped
+func curlQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// tokenListJSON is a token in the json format of FormatTokenList
+type tokenListJSON struct {
+	Provider    string `json:"provider"`
+	Source      string `json:"source"`
+	Token       string `json:"token"`
+	Audience    string `json:"audience,omitempty"`
+	Scope       string `json:"scope,omitempty"`
+	Tenant      string `json:"tenant,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+	ExpiresAt   int64  `json:"expires_at,omitempty"` // Unix seconds; 0 when unknown
+	Expired     bool   `json:"expired"`
+	Refreshable bool   `json:"refreshable"`
+}
+
+// FormatTokenListJSON renders the tokens fetch token --list finds, and the
+// entries that could not be read, as a json object
+func FormatTokenListJSON(tokens []*ExtractedToken, problems []error) (string, error) {
+	out := struct {
+		Tokens  []tokenListJSON `json:"tokens"`
+		Skipped []string        `json:"skipped,omitempty"`
+	}{Tokens: []tokenListJSON{}}
+
+	for _, t := range tokens {
+		token := tokenListJSON{
+			Provider:    t.Provider,
+			Source:      t.Source,
+			Token:       t.Token,
+			Audience:    t.Audience,
+			Scope:       t.Scope,
+			Tenant:      t.Tenant,
+			Subject:     t.Subject,
+			Expired:     t.Expired(0),
+			Refreshable: (t.Auth0 != nil && t.Auth0.CanRefresh()) || (t.Firebase != nil && t.Firebase.CanRefresh()),
+		}
+		if !t.ExpiresAt.IsZero() {
+			token.ExpiresAt = t.ExpiresAt.Unix()
+		}
+		out.Tokens = append(out.Tokens, token)
+	}
+	for _, problem := range problems {
+		out.Skipped = append(out.Skipped, problem.Error())
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token list: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFormatCookies() []*http.Cookie {
+	return []*http.Cookie{
+		{Name: "session", Value: "abc", Domain: ".example.com", Path: "/", Secure: true, HttpOnly: true, Expires: time.Unix(4102444800, 0)},
+		{Name: "theme", Value: "dark mode", Domain: "app.example.com"},
+	}
+}
+
+func TestFormatToken(t *testing.T) {
+	jwt := "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig"
+	cookies := testFormatCookies()
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"text", "JWT=" + jwt + "\nCOOKIE=session=abc; theme=dark mode"},
+		{"env", "export FETCH_JWT=" + jwt + "\nexport FETCH_COOKIE='session=abc; theme=dark mode'"},
+		{"dotenv", "FETCH_JWT=" + jwt + "\nFETCH_COOKIE=\"session=abc; theme=dark mode\""},
+		{"header", "Authorization: Bearer " + jwt},
+		{"curl", `header = "Authorization: Bearer ` + jwt + `"` + "\n" + `header = "Cookie: session=abc; theme=dark mode"`},
+		{"netscape", "# Netscape HTTP Cookie File\n" +
+			"#HttpOnly_.example.com\tTRUE\t/\tTRUE\t4102444800\tsession\tabc\n" +
+			"app.example.com\tFALSE\t/\tFALSE\t0\ttheme\tdark mode"},
+	}
+	for _, tt := range tests {
+		got, err := FormatToken(tt.format, jwt, cookies)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.format, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s:\ngot:  %q\nwant: %q", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestFormatToken_JSON(t *testing.T) {
+	got, err := FormatToken("json", "a.b.c", testFormatCookies())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out struct {
+		JWT          string            `json:"jwt"`
+		CookieHeader string            `json:"cookie_header"`
+		Cookies      []tokenCookieJSON `json:"cookies"`
+	}
+	if err := json.Unmarshal([]byte(got), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	if out.JWT != "a.b.c" || out.CookieHeader != "session=abc; theme=dark mode" || len(out.Cookies) != 2 {
+		t.Fatalf("unexpected output %+v", out)
+	}
+	if c := out.Cookies[0]; !c.Secure || !c.HTTPOnly || c.Expires != 4102444800 || c.Domain != ".example.com" {
+		t.Errorf("unexpected cookie %+v", c)
+	}
+	if c := out.Cookies[1]; c.Expires != 0 {
+		t.Errorf("expected a session cookie without expiry, got %+v", c)
+	}
+
+	got, err = FormatToken("json", "", nil)
+	if err != nil || !strings.Contains(got, `"cookies": []`) || strings.Contains(got, "jwt") {
+		t.Errorf("expected an empty cookie list and no jwt, got %s, %v", got, err)
+	}
+}
+
+func TestFormatToken_Quoting(t *testing.T) {
+	cookies := []*http.Cookie{{Name: "q", Value: `it's "$HOME"`}}
+
+	if got, _ := FormatToken("env", "", cookies); got != `export FETCH_COOKIE='q=it'\''s "$HOME"'` {
+		t.Errorf("env: got %q", got)
+	}
+	if got, _ := FormatToken("dotenv", "", cookies); got != `FETCH_COOKIE="q=it's \"\$HOME\""` {
+		t.Errorf("dotenv: got %q", got)
+	}
+	if got, _ := FormatToken("curl", "", cookies); got != `header = "Cookie: q=it's \"$HOME\""` {
+		t.Errorf("curl: got %q", got)
+	}
+}
+
+func TestFormatToken_Errors(t *testing.T) {
+	if _, err := FormatToken("header", "", testFormatCookies()); err == nil {
+		t.Error("expected an error for a header without a JWT")
+	}
+	if _, err := FormatToken("yaml", "a.b.c", nil); err == nil || !strings.Contains(err.Error(), "netscape") {
+		t.Errorf("expected an error listing the formats, got %v", err)
+	}
+}
+
+func TestFormatTokenListJSON(t *testing.T) {
+	tokens := []*ExtractedToken{
+		{Provider: "auth0", Source: "localStorage", Token: "a.b.c", Audience: "https://api.example.com", ExpiresAt: time.Unix(4102444800, 0),
+			Auth0: &Auth0Tokens{RefreshToken: "r", ClientID: "client", TokenURL: "https://login.example.com/oauth/token"}},
+		{Provider: "jwt", Source: "Authorization header", Token: "d.e.f"},
+	}
+	got, err := FormatTokenListJSON(tokens, []error{errors.New("bad entry")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out struct {
+		Tokens  []tokenListJSON `json:"tokens"`
+		Skipped []string        `json:"skipped"`
+	}
+	if err := json.Unmarshal([]byte(got), &out); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, got)
+	}
+	if len(out.Tokens) != 2 || len(out.Skipped) != 1 || out.Skipped[0] != "bad entry" {
+		t.Fatalf("unexpected output %+v", out)
+	}
+	if tok := out.Tokens[0]; tok.Token != "a.b.c" || tok.Audience != "https://api.example.com" || tok.ExpiresAt != 4102444800 || tok.Expired || !tok.Refreshable {
+		t.Errorf("unexpected token %+v", tok)
+	}
+	if tok := out.Tokens[1]; tok.ExpiresAt != 0 || tok.Refreshable {
+		t.Errorf("expected a token without expiry or refresh, got %+v", tok)
+	}
+
+	got, err = FormatTokenListJSON(nil, nil)
+	if err != nil || !strings.Contains(got, `"tokens": []`) || strings.Contains(got, "skipped") {
+		t.Errorf("expected an empty token list, got %s, %v", got, err)
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -145,3 +146,62 @@ func TestFormatTokenOutput_NeitherPresent(t *testing.T) {
 
 // Ensure time import is used (for future expiry tests)
 var _ = time.Now
+
+func auth0Entry(accessToken string, expiresAt int64) string {
+	return `{"body":{"access_token":"` + accessToken + `","token_type":"Bearer"},"expiresAt":` + strconv.FormatInt(expiresAt, 10) + `}`
+}
+
+func TestSelectAuth0Tokens(t *testing.T) {
+	live := time.Now().Add(time.Hour).Unix()
+	localStorage := map[string]string{
+		"@@auth0spajs@@::client::https://orders.example.com::openid read:orders":                   auth0Entry("orders-token", live),
+		"@@auth0spajs@@::client::https://billing.example.com::openid read:invoices":                auth0Entry("billing-token", live),
+		"@@auth0spajs@@::client::https://billing.example.com::openid read:invoices write:invoices": auth0Entry("billing-write-expired", 1740600000),
+		"@@auth0spajs@@::client::https://broken.example.com::openid":                               `{not valid json`,
+	}
+
+	tests := []struct {
+		name     string
+		selector Auth0Selector
+		want     string
+	}{
+		{"first live token by audience", Auth0Selector{}, "billing-token"},
+		{"audience", Auth0Selector{Audience: "https://orders.example.com/"}, "orders-token"},
+		{"scope", Auth0Selector{Scope: "read:orders"}, "orders-token"},
+		{"expired only match", Auth0Selector{Scope: "write:invoices"}, "billing-write-expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := SelectAuth0Tokens(localStorage, tt.selector)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if tokens.AccessToken != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, tokens.AccessToken)
+			}
+		})
+	}
+
+	_, err := SelectAuth0Tokens(localStorage, Auth0Selector{Audience: "https://unknown.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "https://orders.example.com") {
+		t.Errorf("expected an error listing the available audiences, got: %v", err)
+	}
+}
+
+func TestListAuth0Tokens_SkipsBadEntries(t *testing.T) {
+	localStorage := map[string]string{
+		"@@auth0spajs@@::client::b::openid": auth0Entry("b-token", 9999999999),
+		"@@auth0spajs@@::client::a::openid": auth0Entry("a-token", 9999999999),
+		"@@auth0spajs@@::client::c::openid": `{not valid json`,
+		"@@auth0spajs@@::client::d::openid": `{"body":{}}`,
+		"@@auth0spajs@@::client::@@user@@":  `{"id_token":"x"}`,
+	}
+
+	all, problems := ListAuth0Tokens(localStorage)
+	if len(all) != 2 || all[0].AccessToken != "a-token" || all[1].AccessToken != "b-token" {
+		t.Errorf("expected the two valid entries sorted by audience, got %+v", all)
+	}
+	if len(problems) != 2 {
+		t.Errorf("expected two skipped entries, got: %v", problems)
+	}
+}
@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webDriverDriver implements BrowserDriver over the W3C WebDriver HTTP
+// protocol. Unlike CDP, WebDriver only exposes cookies visible to the
+// current page, so Cookies returns those of the page the login ends on.
+type webDriverDriver struct {
+	baseURL      string // WebDriver server, e.g. http://127.0.0.1:4444
+	httpClient   *http.Client
+	capabilities map[string]interface{}
+	sessionID    string
+	window       string            // Handle of the window Open navigated
+	popups       map[string]string // Popup window handle -> URL it was first seen on
+	server       *exec.Cmd         // Driver process we started, stopped on Close
+}
+
+// newWebDriverDriver creates a driver for a running WebDriver server
+func newWebDriverDriver(baseURL string, capabilities map[string]interface{}) *webDriverDriver {
+	return &webDriverDriver{
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: 60 * time.Second},
+		capabilities: capabilities,
+	}
+}
+
+// webDriverError is the error object WebDriver returns in "value"
+type webDriverError struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+}
+
+// call sends a WebDriver command and decodes the "value" of the response into out
+func (d *webDriverDriver) call(method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal WebDriver request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, d.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create WebDriver request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("WebDriver request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to parse WebDriver response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var wdErr webDriverError
+		if json.Unmarshal(envelope.Value, &wdErr) == nil && wdErr.Error != "" {
+			return fmt.Errorf("WebDriver %s %s: %s: %s", method, path, wdErr.Error, wdErr.Message)
+		}
+		return fmt.Errorf("WebDriver %s %s returned %s", method, path, resp.Status)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(envelope.Value, out); err != nil {
+			return fmt.Errorf("failed to parse WebDriver %s value: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// sessionPath returns a path under the current session
+func (d *webDriverDriver) sessionPath(suffix string) string {
+	return "/session/" + d.sessionID + suffix
+}
+
+// Open starts a WebDriver session (which opens a browser window) and
+// navigates it to the URL
+func (d *webDriverDriver) Open(targetURL string) error {
+	if d.sessionID == "" {
+		request := map[string]interface{}{
+			"capabilities": map[string]interface{}{"alwaysMatch": d.capabilities},
+		}
+		var session struct {
+			SessionID string `json:"sessionId"`
+		}
+		if err := d.call(http.MethodPost, "/session", request, &session); err != nil {
+			return fmt.Errorf("failed to create WebDriver session: %w", err)
+		}
+		d.sessionID = session.SessionID
+
+		if err := d.call(http.MethodGet, d.sessionPath("/window"), nil, &d.window); err != nil {
+			return fmt.Errorf("failed to get WebDriver window: %w", err)
+		}
+	}
+
+	return d.call(http.MethodPost, d.sessionPath("/url"), map[string]string{"url": targetURL}, nil)
+}
+
+// URL returns the current URL of the session's window
+func (d *webDriverDriver) URL() (string, error) {
+	var current string
+	err := d.call(http.MethodGet, d.sessionPath("/url"), nil, &current)
+	return current, err
+}
+
+// webDriverCookie is the W3C WebDriver cookie object
+type webDriverCookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path"`
+	Domain   string `json:"domain"`
+	Secure   bool   `json:"secure"`
+	HTTPOnly bool   `json:"httpOnly"`
+	Expiry   int64  `json:"expiry"`
+	SameSite string `json:"sameSite"`
+}
+
+// Cookies returns the cookies visible to the current page
+func (d *webDriverDriver) Cookies() ([]*http.Cookie, error) {
+	var raw []webDriverCookie
+	if err := d.call(http.MethodGet, d.sessionPath("/cookie"), nil, &raw); err != nil {
+		return nil, fmt.Errorf("failed to get cookies from browser: %w", err)
+	}
+
+	var cookies []*http.Cookie
+	for _, c := range raw {
+		cookie := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+			SameSite: parseSameSite(c.SameSite),
+		}
+		if c.Expiry > 0 {
+			cookie.Expires = time.Unix(c.Expiry, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// Eval runs a JS function expression in the page. WebDriver executes a
+// function body, so the expression is wrapped and invoked; returned promises
+// are awaited by the driver.
+func (d *webDriverDriver) Eval(js string) (string, error) {
+	request := map[string]interface{}{
+		"script": "return (" + js + ")();",
+		"args":   []interface{}{},
+	}
+	var result string
+	if err := d.call(http.MethodPost, d.sessionPath("/execute/sync"), request, &result); err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// Popups returns the URLs of the session's other windows. The session is
+// fetch's own, so every other window was opened by the login flow. Switching
+// windows focuses them on geckodriver and safaridriver, so a popup's URL is
+// read only once, when it first appears; later polls just list the handles.
+func (d *webDriverDriver) Popups() ([]string, error) {
+	var handles []string
+	if err := d.call(http.MethodGet, d.sessionPath("/window/handles"), nil, &handles); err != nil {
+		return nil, fmt.Errorf("failed to list WebDriver windows: %w", err)
+	}
+
+	seen := map[string]string{}
+	switched := false
+	for _, handle := range handles {
+		if handle == d.window {
+			continue
+		}
+		if popupURL, ok := d.popups[handle]; ok {
+			seen[handle] = popupURL
+			continue
+		}
+		// A popup may close between listing and switching; its URL is then
+		// left empty, still counting it as open until it leaves the list
+		seen[handle] = ""
+		if err := d.call(http.MethodPost, d.sessionPath("/window"), map[string]string{"handle": handle}, nil); err != nil {
+			continue
+		}
+		switched = true
+		if current, err := d.URL(); err == nil {
+			seen[handle] = current
+		}
+	}
+	d.popups = seen
+
+	if switched {
+		if err := d.call(http.MethodPost, d.sessionPath("/window"), map[string]string{"handle": d.window}, nil); err != nil {
+			return nil, fmt.Errorf("failed to return to the login window: %w", err)
+		}
+	}
+
+	var urls []string
+	for _, popupURL := range seen {
+		urls = append(urls, popupURL)
+	}
+	return urls, nil
+}
+
+// Close ends the session (closing its window) and stops the driver process
+// if we started it
+func (d *webDriverDriver) Close() error {
+	var err error
+	if d.sessionID != "" {
+		err = d.call(http.MethodDelete, d.sessionPath(""), nil, nil)
+		d.sessionID = ""
+	}
+	if d.server != nil && d.server.Process != nil {
+		_ = d.server.Process.Kill()
+		_ = d.server.Wait()
+		d.server = nil
+	}
+	return err
+}
+
+// webDriverReady checks the WebDriver server's /status endpoint
+func webDriverReady(baseURL string) bool {
+	client := &http.Client{Timeout: 500 * time.Millisecond}
+	resp, err := client.Get(strings.TrimSuffix(baseURL, "/") + "/status")
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	var status struct {
+		Value struct {
+			Ready bool `json:"ready"`
+		} `json:"value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return false
+	}
+	return status.Value.Ready
+}
+
+// WebDriverBaseURL returns the WebDriver server URL for this browser
+func (c *BrowserConfig) WebDriverBaseURL() string {
+	if c.WebDriverURL != "" {
+		return strings.TrimSuffix(c.WebDriverURL, "/")
+	}
+	return fmt.Sprintf("http://127.0.0.1:%d", c.DebugPort)
+}
+
+// IsWebDriverReady checks if the browser's WebDriver server is accepting sessions
+func (c *BrowserConfig) IsWebDriverReady() bool {
+	return webDriverReady(c.WebDriverBaseURL())
+}
+
+// openWebDriver connects to the configured WebDriver server, starting the
+// driver executable (safaridriver, geckodriver, ...) when none is running
+func openWebDriver(config *BrowserConfig) (*webDriverDriver, error) {
+	baseURL := config.WebDriverBaseURL()
+	driver := newWebDriverDriver(baseURL, webDriverCapabilities(config))
+	if webDriverReady(baseURL) || config.WebDriverURL != "" {
+		return driver, nil
+	}
+
+	progressf(config.Progress, "Starting %s WebDriver on port %d...\n", config.Type, config.DebugPort)
+	progressf(config.Progress, "Using %s (%s)\n", config.ExePath, config.ExeReason)
+
+	// Both safaridriver and geckodriver take --port
+	cmd := exec.Command(config.ExePath, "--port", strconv.Itoa(config.DebugPort))
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start WebDriver: %w", err)
+	}
+	driver.server = cmd
+
+	for i := 0; i < 20; i++ {
+		time.Sleep(250 * time.Millisecond)
+		if webDriverReady(baseURL) {
+			return driver, nil
+		}
+	}
+
+	_ = driver.Close()
+	return nil, fmt.Errorf("WebDriver did not become ready on %s after 5 seconds", baseURL)
+}
+
+// webDriverCapabilities returns the session capabilities for a browser.
+// Firefox is pointed at the persistent debug profile so SSO state survives
+// between runs; Safari has no profile selection.
+func webDriverCapabilities(config *BrowserConfig) map[string]interface{} {
+	capabilities := map[string]interface{}{}
+	if config.Type == BrowserFirefox && config.UserDataDir != "" {
+		_ = os.MkdirAll(config.UserDataDir, 0700)
+		capabilities["moz:firefoxOptions"] = map[string]interface{}{
+			"args": []string{"-profile", config.UserDataDir},
+		}
+	}
+	return capabilities
+}
@@ -0,0 +1,262 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWebDriver is a minimal W3C WebDriver server. GET /url walks through
+// redirects, one per request, and then stays on the last URL. Each listing
+// of the window handles moves every popup on to its next URL; a popup
+// closes when it runs out.
+type fakeWebDriver struct {
+	mu        sync.Mutex
+	redirects []string
+	current   string
+	opened    string
+	script    string
+	closed    bool
+	popups    map[string][]string // Popup window handle -> URLs still to visit
+	window    string              // Current window handle; "main" when empty
+	switches  map[string]int      // Times each window was switched to
+}
+
+func (f *fakeWebDriver) reply(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
+
+func (f *fakeWebDriver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/status" {
+		f.reply(w, http.StatusOK, map[string]interface{}{"ready": true})
+		return
+	}
+	if r.Method == http.MethodPost && r.URL.Path == "/session" {
+		f.reply(w, http.StatusOK, map[string]interface{}{"sessionId": "s1", "capabilities": map[string]interface{}{}})
+		return
+	}
+
+	command := strings.TrimPrefix(r.URL.Path, "/session/s1")
+	if command == r.URL.Path {
+		f.reply(w, http.StatusNotFound, map[string]string{"error": "invalid session id", "message": "no such session"})
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && command == "/url":
+		var body struct {
+			URL string `json:"url"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.opened, f.current = body.URL, body.URL
+		f.reply(w, http.StatusOK, nil)
+	case r.Method == http.MethodGet && command == "/window":
+		f.reply(w, http.StatusOK, "main")
+	case r.Method == http.MethodGet && command == "/window/handles":
+		handles := []string{"main"}
+		for handle, urls := range f.popups {
+			if len(urls) > 0 {
+				handles = append(handles, handle)
+				f.popups[handle] = urls[1:]
+			}
+		}
+		f.reply(w, http.StatusOK, handles)
+	case r.Method == http.MethodPost && command == "/window":
+		var body struct {
+			Handle string `json:"handle"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Handle != "main" && len(f.popups[body.Handle]) == 0 {
+			f.reply(w, http.StatusNotFound, map[string]string{"error": "no such window", "message": body.Handle})
+			return
+		}
+		f.window = body.Handle
+		if f.switches == nil {
+			f.switches = map[string]int{}
+		}
+		f.switches[body.Handle]++
+		f.reply(w, http.StatusOK, nil)
+	case r.Method == http.MethodGet && command == "/url" && f.window != "" && f.window != "main":
+		urls := f.popups[f.window]
+		if len(urls) == 0 {
+			f.reply(w, http.StatusNotFound, map[string]string{"error": "no such window", "message": f.window})
+			return
+		}
+		f.reply(w, http.StatusOK, urls[0])
+	case r.Method == http.MethodGet && command == "/url":
+		if len(f.redirects) > 0 {
+			f.current, f.redirects = f.redirects[0], f.redirects[1:]
+		}
+		f.reply(w, http.StatusOK, f.current)
+	case r.Method == http.MethodGet && command == "/cookie":
+		f.reply(w, http.StatusOK, []map[string]interface{}{
+			{"name": "session", "value": "abc", "domain": "app.example.com", "path": "/", "httpOnly": true, "secure": true, "sameSite": "Lax", "expiry": 1893456000},
+		})
+	case r.Method == http.MethodPost && command == "/execute/sync":
+		var body struct {
+			Script string `json:"script"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.script = body.Script
+		f.reply(w, http.StatusOK, `{"token":"xyz"}`)
+	case r.Method == http.MethodDelete && command == "":
+		f.closed = true
+		f.reply(w, http.StatusOK, nil)
+	default:
+		f.reply(w, http.StatusNotFound, map[string]string{"error": "unknown command", "message": r.Method + " " + command})
+	}
+}
+
+func TestWebDriverDriver(t *testing.T) {
+	fake := &fakeWebDriver{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	var driver BrowserDriver = newWebDriverDriver(server.URL, nil)
+	if err := driver.Open("https://app.example.com/"); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	current, err := driver.URL()
+	if err != nil || current != "https://app.example.com/" {
+		t.Errorf("URL() = %q, %v; want the opened URL", current, err)
+	}
+
+	cookies, err := driver.Cookies()
+	if err != nil {
+		t.Fatalf("Cookies failed: %v", err)
+	}
+	if len(cookies) != 1 {
+		t.Fatalf("got %d cookies, want 1", len(cookies))
+	}
+	c := cookies[0]
+	if c.Name != "session" || c.Value != "abc" || !c.HttpOnly || c.SameSite != http.SameSiteLaxMode {
+		t.Errorf("unexpected cookie %+v", c)
+	}
+	if c.Expires.Unix() != 1893456000 {
+		t.Errorf("Expires = %v, want unix 1893456000", c.Expires)
+	}
+
+	result, err := driver.Eval(`() => "x"`)
+	if err != nil || result != `{"token":"xyz"}` {
+		t.Errorf("Eval() = %q, %v", result, err)
+	}
+	if fake.script != `return (() => "x")();` {
+		t.Errorf("script = %q, want the function wrapped and invoked", fake.script)
+	}
+
+	if err := driver.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !fake.closed {
+		t.Error("expected the session to be deleted")
+	}
+}
+
+func TestWebDriverDriver_Error(t *testing.T) {
+	server := httptest.NewServer(&fakeWebDriver{})
+	defer server.Close()
+
+	driver := newWebDriverDriver(server.URL, nil)
+	driver.sessionID = "gone"
+
+	_, err := driver.URL()
+	if err == nil || !strings.Contains(err.Error(), "invalid session id") {
+		t.Errorf("expected the WebDriver error to be surfaced, got %v", err)
+	}
+}
+
+func TestAuthenticateAndCapture_WebDriver(t *testing.T) {
+	fake := &fakeWebDriver{redirects: []string{
+		"https://login.example.net/authorize",
+		"https://app.example.com/landing",
+		"https://app.example.com/landing",
+		"https://app.example.com/landing",
+		"https://app.example.com/home",
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	b := &BrowserAuth{
+		pollInterval:    10 * time.Millisecond,
+		stableThreshold: 30 * time.Millisecond,
+	}
+	b.SetBrowserConfig(&BrowserConfig{
+		Type:         BrowserSafari,
+		Protocol:     ProtocolWebDriver,
+		WebDriverURL: server.URL,
+	})
+
+	result, err := b.AuthenticateAndCapture("https://app.example.com/landing")
+	if err != nil {
+		t.Fatalf("AuthenticateAndCapture failed: %v", err)
+	}
+
+	if fake.opened != "https://app.example.com/landing" {
+		t.Errorf("opened %q, want the target URL", fake.opened)
+	}
+	if fake.current != "https://app.example.com/home" {
+		t.Errorf("login completed on %q, want it to wait past /landing", fake.current)
+	}
+	if len(result.Cookies) != 1 {
+		t.Errorf("got %d cookies, want 1", len(result.Cookies))
+	}
+	if result.LocalStorage["token"] != "xyz" {
+		t.Errorf("LocalStorage = %v, want token xyz", result.LocalStorage)
+	}
+	if !fake.closed {
+		t.Error("expected the session to be closed after capture")
+	}
+}
+
+func TestAuthenticateAndCapture_WebDriverPopup(t *testing.T) {
+	// The app page never leaves its host; the login happens in a popup
+	fake := &fakeWebDriver{popups: map[string][]string{"popup": {
+		"about:blank",
+		"https://login.microsoftonline.com/common/oauth2/v2.0/authorize",
+		"https://login.microsoftonline.com/common/login",
+		"https://app.example.com/auth/callback",
+	}}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	b := &BrowserAuth{
+		pollInterval:    10 * time.Millisecond,
+		stableThreshold: 30 * time.Millisecond,
+	}
+	b.SetBrowserConfig(&BrowserConfig{
+		Type:         BrowserSafari,
+		Protocol:     ProtocolWebDriver,
+		WebDriverURL: server.URL,
+	})
+	var progress strings.Builder
+	b.SetProgress(&progress)
+
+	if _, err := b.AuthenticateAndCapture("https://app.example.com/"); err != nil {
+		t.Fatalf("AuthenticateAndCapture failed: %v", err)
+	}
+
+	if remaining := fake.popups["popup"]; len(remaining) > 0 {
+		t.Errorf("login completed with the popup still open on %v", remaining)
+	}
+	if fake.window != "main" {
+		t.Errorf("capture ran in window %q, want the login window", fake.window)
+	}
+	// Switching focuses the window, so the popup is only visited to read
+	// its first URL
+	if fake.switches["popup"] != 1 || fake.switches["main"] != 1 {
+		t.Errorf("switched windows %v, want one visit to the popup and back", fake.switches)
+	}
+	if got := progress.String(); !strings.Contains(got, "Following login popup: login.microsoftonline.com") || strings.Count(got, "Login popup closed") != 1 {
+		t.Errorf("expected the popup's host and one close in the progress, got %q", got)
+	}
+}
@@ -0,0 +1,231 @@
+package auth
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WSL support: a browser launched on Windows binds its debug port to the
+// Windows loopback, which WSL2 (NAT networking) cannot reach. The bmux setup
+// bridges it with a netsh portproxy listening on port+10000 on all
+// interfaces, reached from WSL through the default gateway (the Windows host).
+
+// wslProxyOffset is added to the debug port to get the portproxy listen port
+const wslProxyOffset = 10000
+
+var (
+	wslOnce     sync.Once
+	wslDetected bool
+)
+
+// IsWSL reports whether fetch is running inside Windows Subsystem for Linux
+func IsWSL() bool {
+	wslOnce.Do(func() {
+		data, err := os.ReadFile("/proc/version")
+		wslDetected = err == nil && isWSLKernel(string(data))
+	})
+	return wslDetected
+}
+
+// isWSLKernel checks a /proc/version string for the WSL kernel signature
+func isWSLKernel(version string) bool {
+	return strings.Contains(strings.ToLower(version), "microsoft")
+}
+
+// WSLProxyPort returns the portproxy port that forwards to a Windows debug port
+func WSLProxyPort(port int) int {
+	return port + wslProxyOffset
+}
+
+// wslUsesNAT reports whether WSL networking is in NAT mode. In mirrored mode
+// Windows loopback ports are reachable as localhost and no bridge is needed.
+func wslUsesNAT() bool {
+	out, err := exec.Command("wslinfo", "--networking-mode").Output()
+	if err != nil {
+		// Older WSL without wslinfo only supports NAT
+		return true
+	}
+	return strings.TrimSpace(string(out)) != "mirrored"
+}
+
+// WSLHostIP returns the Windows host's IP as seen from WSL (the default gateway)
+func WSLHostIP() (string, error) {
+	data, err := os.ReadFile("/proc/net/route")
+	if err != nil {
+		return "", fmt.Errorf("failed to read routing table: %w", err)
+	}
+	return parseDefaultGateway(string(data))
+}
+
+// parseDefaultGateway finds the default route's gateway in /proc/net/route
+// content. Addresses there are little-endian hex.
+func parseDefaultGateway(routeTable string) (string, error) {
+	scanner := bufio.NewScanner(strings.NewReader(routeTable))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+
+		raw, err := hex.DecodeString(fields[2])
+		if err != nil || len(raw) != 4 {
+			continue
+		}
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, binary.LittleEndian.Uint32(raw))
+		return ip.String(), nil
+	}
+	return "", fmt.Errorf("no default route found")
+}
+
+// applyWSLBridge routes the debug connection through the Windows host and
+// portproxy when running in WSL with NAT networking. No-op elsewhere.
+func applyWSLBridge(config *BrowserConfig) {
+	if !IsWSL() || !wslUsesNAT() {
+		return
+	}
+	host, err := WSLHostIP()
+	if err != nil {
+		return
+	}
+	config.DebugHost = host
+	config.WSLBridge = true
+}
+
+var (
+	windowsEnvMu    sync.Mutex
+	windowsEnvCache = map[string]string{}
+)
+
+// windowsGetenv reads a Windows environment variable through interop.
+// Results are cached since each lookup spawns cmd.exe.
+func windowsGetenv(key string) string {
+	windowsEnvMu.Lock()
+	defer windowsEnvMu.Unlock()
+
+	if value, ok := windowsEnvCache[key]; ok {
+		return value
+	}
+
+	cmd := exec.Command("cmd.exe", "/c", "echo %"+key+"%")
+	// cmd.exe refuses a Linux working directory (UNC path), so run from C:
+	cmd.Dir = "/mnt/c"
+	out, err := cmd.Output()
+
+	value := strings.TrimSpace(string(out))
+	if err != nil || value == "%"+key+"%" {
+		value = ""
+	}
+	windowsEnvCache[key] = value
+	return value
+}
+
+// wslMountPath converts a Windows path (C:\x or C:/x) to its /mnt/c/x form.
+// Paths without a drive letter are returned unchanged.
+func wslMountPath(windowsPath string) string {
+	if len(windowsPath) < 2 || windowsPath[1] != ':' {
+		return windowsPath
+	}
+	drive := strings.ToLower(windowsPath[:1])
+	rest := strings.ReplaceAll(windowsPath[2:], `\`, "/")
+	return "/mnt/" + drive + rest
+}
+
+// windowsStylePath converts forward slashes to backslashes for paths handed
+// to Windows programs
+func windowsStylePath(p string) string {
+	return strings.ReplaceAll(p, "/", `\`)
+}
+
+// WSLBridgeDiagnostics explains why the debug port of a Windows browser is
+// not reachable from WSL, checking each hop of the bridge. Each entry is one
+// finding; fixes are included where a check fails.
+func WSLBridgeDiagnostics(config *BrowserConfig) []string {
+	port := config.DebugPort
+	proxyPort := WSLProxyPort(port)
+	var findings []string
+
+	if out, err := exec.Command("netstat.exe", "-an", "-p", "TCP").Output(); err != nil {
+		findings = append(findings, fmt.Sprintf("could not run netstat.exe through interop: %v", err))
+	} else if !netstatListening(string(out), port) {
+		findings = append(findings, fmt.Sprintf(
+			"nothing is listening on Windows 127.0.0.1:%d - is %s running with --remote-debugging-port=%d?",
+			port, config.Type, port))
+	} else {
+		findings = append(findings, fmt.Sprintf("ok: %s is listening on Windows port %d", config.Type, port))
+	}
+
+	if out, err := exec.Command("netsh.exe", "interface", "portproxy", "show", "v4tov4").Output(); err != nil {
+		findings = append(findings, fmt.Sprintf("could not run netsh.exe through interop: %v", err))
+	} else if target, ok := portProxyTarget(string(out), proxyPort); !ok {
+		findings = append(findings, fmt.Sprintf(
+			"no portproxy listens on %d; in an elevated PowerShell run:\n"+
+				"  netsh interface portproxy add v4tov4 listenaddress=0.0.0.0 listenport=%d connectaddress=127.0.0.1 connectport=%d",
+			proxyPort, proxyPort, port))
+	} else if target != port {
+		findings = append(findings, fmt.Sprintf(
+			"portproxy on %d forwards to port %d, expected %d", proxyPort, target, port))
+	} else {
+		findings = append(findings, fmt.Sprintf("ok: portproxy forwards %d -> %d", proxyPort, port))
+	}
+
+	address := net.JoinHostPort(config.DebugHost, strconv.Itoa(proxyPort))
+	conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+	if err != nil {
+		findings = append(findings, fmt.Sprintf(
+			"cannot reach %s from WSL (%v); the firewall is likely blocking it. In an elevated PowerShell run:\n"+
+				"  netsh advfirewall firewall add rule name=\"CDP Debug Port\" dir=in action=allow protocol=TCP localport=%d\n"+
+				"  Set-NetFirewallHyperVVMSetting -Name '{40E0AC32-46A5-438A-A0B2-2B479E8F2E90}' -DefaultInboundAction Allow\n"+
+				"(the Hyper-V setting resets when WSL restarts)",
+			address, err, proxyPort))
+	} else {
+		conn.Close()
+		findings = append(findings, fmt.Sprintf("ok: %s is reachable from WSL", address))
+	}
+
+	return findings
+}
+
+// netstatListening checks netstat.exe output for a listener on a local port
+func netstatListening(netstat string, port int) bool {
+	suffix := ":" + strconv.Itoa(port)
+	scanner := bufio.NewScanner(strings.NewReader(netstat))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.EqualFold(fields[0], "TCP") {
+			continue
+		}
+		if strings.HasSuffix(fields[1], suffix) && strings.EqualFold(fields[3], "LISTENING") {
+			return true
+		}
+	}
+	return false
+}
+
+// portProxyTarget finds the connect port for a listen port in
+// `netsh interface portproxy show v4tov4` output
+func portProxyTarget(netsh string, listenPort int) (int, bool) {
+	scanner := bufio.NewScanner(strings.NewReader(netsh))
+	for scanner.Scan() {
+		// Columns: listen address, listen port, connect address, connect port
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] != strconv.Itoa(listenPort) {
+			continue
+		}
+		target, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+		return target, true
+	}
+	return 0, false
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestIsWSLKernel(t *testing.T) {
+	wsl := "Linux version 5.15.153.1-microsoft-standard-WSL2 (root@941d701f84f1) (gcc (GCC) 11.2.0) #1 SMP"
+	if !isWSLKernel(wsl) {
+		t.Error("expected WSL2 kernel to be detected")
+	}
+
+	native := "Linux version 6.8.0-45-generic (buildd@lcy02-amd64-115) (x86_64-linux-gnu-gcc-13) #45-Ubuntu SMP"
+	if isWSLKernel(native) {
+		t.Error("expected native kernel not to be detected as WSL")
+	}
+}
+
+func TestParseDefaultGateway(t *testing.T) {
+	routes := `Iface	Destination	Gateway 	Flags	RefCnt	Use	Metric	Mask		MTU	Window	IRTT
+eth0	00000000	0100A8AC	0003	0	0	0	00000000	0	0	0
+eth0	0000A8AC	00000000	0001	0	0	0	00F0FFFF	0	0	0
+`
+	ip, err := parseDefaultGateway(routes)
+	if err != nil {
+		t.Fatalf("parseDefaultGateway failed: %v", err)
+	}
+	if ip != "172.168.0.1" {
+		t.Errorf("gateway = %s, want 172.168.0.1", ip)
+	}
+
+	_, err = parseDefaultGateway("Iface	Destination	Gateway\neth0	0000A8AC	00000000\n")
+	if err == nil {
+		t.Error("expected error when there is no default route")
+	}
+}
+
+func TestWSLMountPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`C:\Program Files\Microsoft\Edge\Application\msedge.exe`, "/mnt/c/Program Files/Microsoft/Edge/Application/msedge.exe"},
+		{"C:/Program Files (x86)/Microsoft/Edge/Application/msedge.exe", "/mnt/c/Program Files (x86)/Microsoft/Edge/Application/msedge.exe"},
+		{`D:\Apps\chrome.exe`, "/mnt/d/Apps/chrome.exe"},
+		{"/usr/bin/chrome", "/usr/bin/chrome"},
+	}
+
+	for _, tt := range tests {
+		if got := wslMountPath(tt.in); got != tt.want {
+			t.Errorf("wslMountPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDiscover_WSLFindsWindowsBrowser(t *testing.T) {
+	env := fakeDiscoveryEnv("wsl", nil,
+		"/mnt/c/Program Files (x86)/Microsoft/Edge/Application/msedge.exe",
+	)
+
+	config := env.browserConfig(builtinDefinition(t, BrowserEdge))
+	if config.ExePath != "/mnt/c/Program Files (x86)/Microsoft/Edge/Application/msedge.exe" {
+		t.Errorf("ExePath = %q, want Windows Edge under /mnt/c", config.ExePath)
+	}
+	if config.UserDataDir != `C:\Users\dev\AppData\Local\Microsoft\EdgeDebug` {
+		t.Errorf("UserDataDir = %q, want Windows-style profile path", config.UserDataDir)
+	}
+}
+
+func TestBrowserConfig_WSLBridgeURL(t *testing.T) {
+	config := &BrowserConfig{DebugPort: 9222, DebugHost: "172.22.0.1", WSLBridge: true}
+	if got := config.DebugURL(); got != "http://172.22.0.1:19222" {
+		t.Errorf("DebugURL() = %q, want portproxy URL", got)
+	}
+
+	config = &BrowserConfig{DebugPort: 9222}
+	if got := config.DebugURL(); got != "http://127.0.0.1:9222" {
+		t.Errorf("DebugURL() = %q, want loopback URL", got)
+	}
+}
+
+func TestPortProxyTarget(t *testing.T) {
+	netsh := `
+Listen on ipv4:             Connect to ipv4:
+
+Address         Port        Address         Port
+--------------- ----------  --------------- ----------
+0.0.0.0         19222       127.0.0.1       9222
+0.0.0.0         19223       127.0.0.1       9300
+`
+	if target, ok := portProxyTarget(netsh, 19222); !ok || target != 9222 {
+		t.Errorf("portProxyTarget(19222) = %d, %v; want 9222, true", target, ok)
+	}
+	if target, ok := portProxyTarget(netsh, 19223); !ok || target != 9300 {
+		t.Errorf("portProxyTarget(19223) = %d, %v; want 9300, true", target, ok)
+	}
+	if _, ok := portProxyTarget(netsh, 19224); ok {
+		t.Error("expected no portproxy for 19224")
+	}
+}
+
+func TestNetstatListening(t *testing.T) {
+	netstat := `
+Active Connections
+
+  Proto  Local Address          Foreign Address        State
+  TCP    127.0.0.1:9222         0.0.0.0:0              LISTENING
+  TCP    127.0.0.1:52311        127.0.0.1:9223         ESTABLISHED
+`
+	if !netstatListening(netstat, 9222) {
+		t.Error("expected port 9222 to be listening")
+	}
+	if netstatListening(netstat, 9223) {
+		t.Error("port 9223 only appears as a remote address and should not count")
+	}
+}
@@ -2,37 +2,70 @@ package cli
 
 import (
 	"fmt"
+	"time"
 
-	"github.com/omaticsoftware/fetch/internal/client"
+	"github.com/omaticsoftware/fetch/internal/auth"
 	"github.com/spf13/cobra"
 )
 
 // authCmd represents the auth command
 var authCmd = &cobra.Command{
-	Use:   "auth <url>",
-	Short: "Force re-authentication for a URL",
+	Use:   "auth <url>...",
+	Short: "Force re-authentication for one or more URLs",
 	Long: `Force browser-based authentication for the specified URL.
 This will clear any existing cached session for the host and
-trigger a new browser authentication flow.`,
-	Args: cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) error {
-		targetURL := args[0]
+trigger a new browser authentication flow.
 
-		c, err := client.NewClientWithBrowser(GetBrowserType())
+With several URLs, each opens in its own tab of one browser session. Each
+login completes on its own and its session is saved as soon as it does;
+a summary follows once all are done:
+  fetch auth https://app1.example.com https://app2.example.com`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
 		}
 
-		fmt.Printf("Authenticating to %s...\n", targetURL)
-		if err := c.Authenticate(targetURL); err != nil {
-			return fmt.Errorf("authentication failed: %w", err)
+		if len(args) == 1 {
+			targetURL := args[0]
+			fmt.Printf("Authenticating to %s...\n", targetURL)
+			if err := c.Authenticate(targetURL); err != nil {
+				return fmt.Errorf("authentication failed: %w", err)
+			}
+
+			fmt.Println("Authentication successful!")
+			return nil
 		}
 
-		fmt.Println("Authentication successful!")
-		return nil
+		outcomes, err := c.AuthenticateAll(args)
+		if err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+		return printAuthSummary(outcomes)
 	},
 }
 
+// printAuthSummary prints one line per URL and fails if any login did
+func printAuthSummary(outcomes []*auth.AuthOutcome) error {
+	failed := 0
+	fmt.Println("\nSummary:")
+	for _, o := range outcomes {
+		if o.Err != nil {
+			failed++
+			fmt.Printf("  FAILED  %s: %v\n", o.URL, o.Err)
+			continue
+		}
+		fmt.Printf("  OK      %s (%d cookies, %s)\n", o.Host, o.Cookies, o.Duration.Round(time.Second))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d logins failed", failed, len(outcomes))
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(authCmd)
+	addBmuxSessionFlag(authCmd)
 }
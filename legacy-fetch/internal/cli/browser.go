@@ -0,0 +1,275 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/omaticsoftware/fetch/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+// browserCmd groups the debug-browser lifecycle commands
+var browserCmd = &cobra.Command{
+	Use:   "browser",
+	Short: "Manage the debug browser fetch launches",
+	Long: `Start, stop and inspect the debug browser used for authentication.
+
+Browsers launched by fetch are recorded in ~/.omatic/browsers.json with
+their PID, debug port and profile directory, so later runs can reattach
+to them or shut them down cleanly.`,
+}
+
+var browserStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Launch the debug browser and leave it running",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := GetBrowserConfig()
+		if err != nil {
+			return err
+		}
+
+		launched, alreadyRunning, err := auth.StartBrowser(config)
+		if err != nil {
+			return fmt.Errorf("failed to start browser: %w", err)
+		}
+
+		if alreadyRunning {
+			if launched != nil {
+				fmt.Printf("%s is already running (PID %d, port %d)\n", config.Type, launched.PID, launched.Port)
+			} else {
+				fmt.Printf("A browser not launched by fetch is already listening on port %d\n", config.DebugPort)
+			}
+			return nil
+		}
+
+		fmt.Printf("Started %s (PID %d, port %d)\n", launched.Type, launched.PID, launched.Port)
+		return nil
+	},
+}
+
+var browserStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Shut down the debug browser fetch launched",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := GetBrowserConfig()
+		if err != nil {
+			return err
+		}
+
+		stopped, wasRunning, err := auth.StopBrowser(config)
+		if err != nil {
+			return fmt.Errorf("failed to stop browser: %w", err)
+		}
+
+		if !wasRunning {
+			fmt.Printf("%s (PID %d) had already exited\n", stopped.Type, stopped.PID)
+			return nil
+		}
+		fmt.Printf("Stopped %s (PID %d)\n", stopped.Type, stopped.PID)
+		return nil
+	},
+}
+
+var browserStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the state of the debug browser",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := GetBrowserConfig()
+		if err != nil {
+			return err
+		}
+
+		store, err := auth.NewBrowserStateStore()
+		if err != nil {
+			return err
+		}
+		record, err := store.Get(config.Type)
+		if err != nil {
+			return err
+		}
+
+		port := config.DebugPort
+		if record != nil {
+			port = record.Port
+		}
+		probe := *config
+		probe.DebugPort = port
+
+		fmt.Printf("Browser:    %s\n", config.Type)
+		fmt.Printf("Executable: %s (%s)\n", config.ExePath, config.ExeReason)
+		fmt.Printf("Profile:    %s\n", config.UserDataDir)
+		if config.Protocol == auth.ProtocolWebDriver {
+			fmt.Printf("WebDriver:  %s (%s)\n", config.WebDriverBaseURL(), openOrClosed(config.IsWebDriverReady()))
+			return nil
+		}
+		portOpen := probe.IsDebugPortOpen()
+		fmt.Printf("Debug port: %d via %s (%s)\n", port, probe.DebugURL(), openOrClosed(portOpen))
+		if portOpen {
+			if version, err := probe.DebugVersion(); err != nil {
+				fmt.Printf("Serving:    %v\n", err)
+			} else if err := version.Matches(config.Type); err != nil {
+				fmt.Printf("Serving:    %s - mismatch: %v\n", version.Product(), err)
+			} else {
+				fmt.Printf("Serving:    %s, protocol %s\n", version.Product(), version.ProtocolVersion)
+			}
+		}
+
+		if probe.WSLBridge && !portOpen {
+			fmt.Println("WSL bridge diagnostics:")
+			for _, finding := range auth.WSLBridgeDiagnostics(&probe) {
+				fmt.Printf("  - %s\n", finding)
+			}
+		}
+
+		if record == nil {
+			fmt.Println("Launched by fetch: no")
+			return nil
+		}
+
+		state := "running"
+		if !record.Alive() {
+			state = "dead"
+		}
+		fmt.Printf("Launched by fetch: PID %d, %s, started %s\n",
+			record.PID, state, record.StartedAt.Format(time.RFC3339))
+		return nil
+	},
+}
+
+var browserProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List the profiles in the debug browser's user-data dir",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := GetBrowserConfig()
+		if err != nil {
+			return err
+		}
+
+		profiles, err := auth.ListProfiles(config.UserDataDir)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Profiles in %s (%d):\n", config.UserDataDir, len(profiles))
+		for _, p := range profiles {
+			line := fmt.Sprintf("  - %s: %s", p.Directory, p.Name)
+			if p.UserName != "" {
+				line += fmt.Sprintf(" <%s>", p.UserName)
+			}
+			if p.LastUsed {
+				line += " (default)"
+			}
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+var (
+	cloneFromFlag  string
+	cloneForceFlag bool
+)
+
+var browserCloneProfileCmd = &cobra.Command{
+	Use:   "clone-profile [profile]",
+	Short: "Copy an everyday browser profile into the debug user-data dir",
+	Long: `Copy a profile from the browser's everyday user-data dir into the debug
+user-data dir fetch launches, so the debug browser starts out with your
+existing SSO cookies, saved logins and preferences.
+
+The profile is chosen by name, directory or account as for --browser-profile;
+without one, the browser's default profile is copied. Caches and lock files
+are left behind. The browser must be fully closed while copying.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := GetBrowserConfig()
+		if err != nil {
+			return err
+		}
+
+		source := cloneFromFlag
+		if source == "" {
+			source = config.SourceUserDataDir
+		}
+		if source == "" {
+			return fmt.Errorf("no everyday user-data dir is known for %s; pass --from", config.Type)
+		}
+
+		profileName := ""
+		if len(args) == 1 {
+			profileName = args[0]
+		}
+
+		fmt.Printf("Cloning %s profile\n  From: %s\n  To:   %s\n", config.Type, source, config.UserDataDir)
+		result, err := auth.CloneProfile(source, profileName, config.UserDataDir, cloneForceFlag)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Copied %q (%s): %d files, %.1f MB; skipped %d caches and lock files\n",
+			result.Profile.Name, result.Profile.Directory, result.Files, float64(result.Bytes)/(1<<20), result.Skipped)
+		fmt.Printf("Log in with it using: fetch --browser %s --browser-profile %q\n", config.Type, result.Profile.Directory)
+		return nil
+	},
+}
+
+var browserListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List browsers launched by fetch",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := auth.NewBrowserStateStore()
+		if err != nil {
+			return err
+		}
+
+		pruned, err := store.Prune()
+		if err != nil {
+			return err
+		}
+		if pruned > 0 {
+			fmt.Printf("Pruned %d stale browser record(s).\n", pruned)
+		}
+
+		browsers, err := store.List()
+		if err != nil {
+			return err
+		}
+
+		if len(browsers) == 0 {
+			fmt.Println("No browsers launched by fetch are running.")
+			return nil
+		}
+
+		fmt.Printf("Launched browsers (%d):\n", len(browsers))
+		for _, b := range browsers {
+			fmt.Printf("  - %s: PID %d, port %d, profile %s\n", b.Type, b.PID, b.Port, b.UserDataDir)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(browserCmd)
+	browserCmd.AddCommand(browserStartCmd)
+	browserCmd.AddCommand(browserStopCmd)
+	browserCmd.AddCommand(browserStatusCmd)
+	browserCmd.AddCommand(browserListCmd)
+	browserCmd.AddCommand(browserProfilesCmd)
+	browserCmd.AddCommand(browserCloneProfileCmd)
+
+	browserCloneProfileCmd.Flags().StringVar(&cloneFromFlag, "from", "", "User-data dir to copy from (default: the browser's everyday one)")
+	browserCloneProfileCmd.Flags().BoolVar(&cloneForceFlag, "force", false, "Overwrite a debug user-data dir that already holds a profile")
+}
+
+// openOrClosed describes a debug port probe result
+func openOrClosed(open bool) string {
+	if open {
+		return "responding"
+	}
+	return "not responding"
+}
@@ -23,7 +23,7 @@ var getCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		targetURL := args[0]
 
-		c, err := client.NewClientWithBrowser(GetBrowserType())
+		c, err := newClient()
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
 		}
@@ -52,7 +52,7 @@ var postCmd = &cobra.Command{
 			body = []byte(dataFlag)
 		}
 
-		c, err := client.NewClientWithBrowser(GetBrowserType())
+		c, err := newClient()
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
 		}
@@ -87,7 +87,7 @@ var putCmd = &cobra.Command{
 			body = []byte(dataFlag)
 		}
 
-		c, err := client.NewClientWithBrowser(GetBrowserType())
+		c, err := newClient()
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
 		}
@@ -130,7 +130,7 @@ var putCmd = &cobra.Command{
 			fmt.Printf("Session expired for %s, re-authenticating...\n", host)
 			resp.Body.Close()
 
-			if err := c.Authenticate(targetURL); err != nil {
+			if err := c.Reauthenticate(targetURL); err != nil {
 				return fmt.Errorf("re-authentication failed: %w", err)
 			}
 
@@ -155,7 +155,7 @@ var deleteCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		targetURL := args[0]
 
-		c, err := client.NewClientWithBrowser(GetBrowserType())
+		c, err := newClient()
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
 		}
@@ -191,7 +191,7 @@ var deleteCmd = &cobra.Command{
 			fmt.Printf("Session expired for %s, re-authenticating...\n", host)
 			resp.Body.Close()
 
-			if err := c.Authenticate(targetURL); err != nil {
+			if err := c.Reauthenticate(targetURL); err != nil {
 				return fmt.Errorf("re-authentication failed: %w", err)
 			}
 
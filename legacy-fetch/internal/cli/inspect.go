@@ -4,8 +4,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-rod/rod"
-	"github.com/go-rod/rod/lib/launcher"
+	"github.com/omaticsoftware/fetch/internal/auth"
 	"github.com/spf13/cobra"
 )
 
@@ -16,19 +15,19 @@ var inspectCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		targetURL := args[0]
 
-		fmt.Println("Connecting to browser...")
-		u, err := launcher.ResolveURL("localhost:9222")
+		config, err := GetBrowserConfig()
 		if err != nil {
-			fmt.Println("No existing browser, launching Edge...")
-			edgePath := `C:\Program Files (x86)\Microsoft\Edge\Application\msedge.exe`
-			l := launcher.New().Bin(edgePath).Headless(false)
-			u = l.MustLaunch()
+			return err
 		}
 
-		browser := rod.New().ControlURL(u)
-		if err := browser.Connect(); err != nil {
+		fmt.Println("Connecting to browser...")
+		browser, owned, err := auth.ConnectBrowser(config)
+		if err != nil {
 			return fmt.Errorf("failed to connect: %w", err)
 		}
+		if owned {
+			defer browser.MustClose()
+		}
 
 		page := browser.MustPage(targetURL)
 		defer page.MustClose()
@@ -83,4 +82,5 @@ var inspectCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(inspectCmd)
+	addBmuxSessionFlag(inspectCmd)
 }
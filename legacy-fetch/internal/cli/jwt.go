@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/omaticsoftware/fetch/internal/auth"
+	"github.com/omaticsoftware/fetch/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var (
+	jwtJSONFlag     bool
+	jwtSessionFlag  string
+	jwtIssuerFlag   string
+	jwtAudienceFlag string
+	jwtJWKSFlag     string
+	jwtLeewayFlag   time.Duration
+)
+
+// jwtCmd groups the commands that inspect tokens locally
+var jwtCmd = &cobra.Command{
+	Use:   "jwt",
+	Short: "Inspect JWTs locally",
+	Long: `Inspect the JWTs fetch hands out without pasting them into a website.
+Decoding sends nothing over the network; verifying only fetches the
+issuer's public keys.`,
+}
+
+var jwtDecodeCmd = &cobra.Command{
+	Use:   "decode [token|-]",
+	Short: "Decode a JWT's header and claims",
+	Long: `Decode a JWT's header and claims and show its issuer, audience, scopes or
+roles, tenant and the time remaining until it expires. The signature is
+not checked.
+
+The token is read from the argument, from stdin when the argument is "-" or
+missing, or with --session from the token "fetch token" stored for a host.
+Only Auth0 and Firebase tokens are stored; pipe the others in:
+  fetch token https://app.example.com --provider msal | fetch jwt decode
+
+A "JWT=" line, an "Authorization:" header or a "Bearer " prefix is accepted
+as pasted:
+  fetch token https://app.example.com --format header | fetch jwt decode
+  fetch jwt decode --session app.example.com --json
+
+Exits non-zero when the token has expired, so scripts can gate on it.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		input, err := jwtInput(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		jwt, err := auth.DecodeJWT(auth.TrimJWT(input))
+		if err != nil {
+			return err
+		}
+		summary := jwt.Summarize(time.Now())
+
+		if jwtJSONFlag {
+			data, err := json.MarshalIndent(summary, "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal JWT: %w", err)
+			}
+			fmt.Println(string(data))
+		} else if err := printJWTSummary(summary); err != nil {
+			return err
+		}
+
+		if summary.Expired {
+			return fmt.Errorf("token expired %s ago", time.Since(*summary.ExpiresAt).Round(time.Second))
+		}
+		return nil
+	},
+}
+
+var jwtVerifyCmd = &cobra.Command{
+	Use:   "verify [token|-]",
+	Short: "Verify a JWT's signature and claims",
+	Long: `Verify that a JWT was signed by its issuer and is meant for your API.
+
+The signature is checked with the JSON Web Key Set of the issuer given with
+--issuer, discovered through <issuer>/.well-known/openid-configuration and
+cached in ~/.omatic/jwks for a day, or with a local JWKS file given with
+--jwks. One of them is required: the token's own iss claim is never used to
+find keys, since anyone can sign a token naming an issuer they host. Issuers
+must use https, except on localhost.
+
+RS256, PS256 and ES256 are supported. The token must have an exp claim;
+exp and nbf are always checked (with --leeway for clock skew), iss against
+--issuer and aud against --audience.
+
+The token is read as for "fetch jwt decode":
+  fetch jwt verify --session app.example.com --issuer https://tenant.auth0.com/ --audience https://api.example.com
+
+Exits non-zero when the token does not verify.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if jwtIssuerFlag == "" && jwtJWKSFlag == "" {
+			return fmt.Errorf("give the issuer to trust with --issuer, or its keys with --jwks")
+		}
+
+		input, err := jwtInput(cmd, args)
+		if err != nil {
+			return err
+		}
+
+		jwt, err := auth.DecodeJWT(auth.TrimJWT(input))
+		if err != nil {
+			return err
+		}
+
+		cache, err := auth.NewJWKSCache(&http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			return err
+		}
+		opts := auth.VerifyOptions{
+			ClaimExpectations: auth.ClaimExpectations{
+				Issuer:   jwtIssuerFlag,
+				Audience: jwtAudienceFlag,
+				Leeway:   jwtLeewayFlag,
+			},
+			JWKSFile: jwtJWKSFlag,
+		}
+		if err := auth.VerifyJWT(jwt, opts, cache, time.Now()); err != nil {
+			return fmt.Errorf("token did not verify: %w", err)
+		}
+
+		fmt.Printf("Signature valid (%v, kid %v)\n", jwt.Header["alg"], jwt.Header["kid"])
+		fmt.Printf("Issuer:    %s\n", jwt.StringClaim("iss"))
+		fmt.Printf("Audience:  %s\n", strings.Join(jwt.Audiences(), ", "))
+		if exp, ok := jwt.TimeClaim("exp"); ok {
+			fmt.Printf("Expires:   %s (in %s)\n", exp.Format(time.RFC3339), time.Until(exp).Round(time.Second))
+		}
+		return nil
+	},
+}
+
+// jwtInput reads the token to decode from the argument, a stored session or
+// stdin
+func jwtInput(cmd *cobra.Command, args []string) (string, error) {
+	if jwtSessionFlag != "" {
+		if len(args) > 0 {
+			return "", fmt.Errorf("give either a token or --session, not both")
+		}
+		c, err := client.NewClient()
+		if err != nil {
+			return "", fmt.Errorf("failed to create client: %w", err)
+		}
+		return c.SessionToken(jwtSessionFlag)
+	}
+
+	if len(args) > 0 && args[0] != "-" {
+		return args[0], nil
+	}
+	data, err := io.ReadAll(cmd.InOrStdin())
+	if err != nil {
+		return "", fmt.Errorf("failed to read token from stdin: %w", err)
+	}
+	// Take the JWT line when fetch token's whole output is piped in
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "JWT=") {
+			return line, nil
+		}
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("no token given: pass it as an argument, on stdin or with --session")
+	}
+	return string(data), nil
+}
+
+// printJWTSummary prints a decoded token for reading
+func printJWTSummary(summary *auth.JWTSummary) error {
+	var header []string
+	for _, name := range []string{"alg", "typ", "kid"} {
+		if value, ok := summary.Header[name]; ok {
+			header = append(header, fmt.Sprintf("%s=%v", name, value))
+		}
+	}
+	fmt.Printf("Header:    %s\n", strings.Join(header, " "))
+
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Printf("%-10s %s\n", name+":", value)
+		}
+	}
+	field("Issuer", summary.Issuer)
+	field("Subject", summary.Subject)
+	field("Audience", strings.Join(summary.Audiences, ", "))
+	field("Scopes", strings.Join(summary.Scopes, " "))
+	field("Roles", strings.Join(summary.Roles, ", "))
+	field("Tenant", summary.Tenant)
+	if summary.IssuedAt != nil {
+		field("Issued", summary.IssuedAt.Format(time.RFC3339))
+	}
+	switch {
+	case summary.ExpiresAt == nil:
+		field("Expires", "never (no exp claim)")
+	case summary.Expired:
+		field("Expires", fmt.Sprintf("%s (expired %s ago)", summary.ExpiresAt.Format(time.RFC3339), time.Since(*summary.ExpiresAt).Round(time.Second)))
+	default:
+		field("Expires", fmt.Sprintf("%s (in %s)", summary.ExpiresAt.Format(time.RFC3339), time.Until(*summary.ExpiresAt).Round(time.Second)))
+	}
+
+	claims, err := json.MarshalIndent(summary.Claims, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal claims: %w", err)
+	}
+	fmt.Printf("Claims:\n%s\n", claims)
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(jwtCmd)
+	jwtCmd.AddCommand(jwtDecodeCmd)
+	jwtDecodeCmd.Flags().BoolVar(&jwtJSONFlag, "json", false, "Print the header, claims and summary as JSON")
+	jwtDecodeCmd.Flags().StringVar(&jwtSessionFlag, "session", "", "Decode the Auth0 or Firebase token stored for this host by \"fetch token\"")
+
+	jwtCmd.AddCommand(jwtVerifyCmd)
+	jwtVerifyCmd.Flags().StringVar(&jwtSessionFlag, "session", "", "Verify the Auth0 or Firebase token stored for this host by \"fetch token\"")
+	jwtVerifyCmd.Flags().StringVar(&jwtIssuerFlag, "issuer", "", "Expected iss; its OpenID configuration gives the JWKS")
+	jwtVerifyCmd.Flags().StringVar(&jwtAudienceFlag, "audience", "", "Audience the token's aud must include")
+	jwtVerifyCmd.Flags().StringVar(&jwtJWKSFlag, "jwks", "", "Verify with this local JWKS file instead of discovering the issuer's")
+	jwtVerifyCmd.Flags().DurationVar(&jwtLeewayFlag, "leeway", time.Minute, "Allowed clock skew for exp and nbf")
+}
@@ -1,11 +1,25 @@
 package cli
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/omaticsoftware/fetch/internal/auth"
+	"github.com/omaticsoftware/fetch/internal/client"
 	"github.com/spf13/cobra"
 )
 
-var browserFlag string
+var (
+	browserFlag        string
+	cdpURLFlag         string
+	connectTimeoutFlag time.Duration
+	webDriverURLFlag   string
+	launchModeFlag     string
+	browserProfileFlag string
+	bmuxSessionFlag    string
+	reuseTabFlag       bool
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "fetch",
@@ -14,8 +28,51 @@ var rootCmd = &cobra.Command{
 It automatically handles browser-based authentication and caches sessions.
 
 Use --browser to select which browser to use:
-  edge   - Microsoft Edge (default, good for work/SSO)
-  chrome - Google Chrome (good for personal accounts)`,
+  edge     - Microsoft Edge (default, good for work/SSO)
+  chrome   - Google Chrome (good for personal accounts)
+  brave    - Brave
+  chromium - Chromium
+  vivaldi  - Vivaldi
+  firefox  - Mozilla Firefox, driven through geckodriver (WebDriver)
+  safari   - Safari, driven through safaridriver (WebDriver; run
+             "safaridriver --enable" once first)
+
+Use --cdp-url to drive a browser that is already running elsewhere, such as
+headless Chromium in a container, instead of launching one:
+  --cdp-url http://host:9222                  (discovery endpoint)
+  --cdp-url ws://host:9222/devtools/browser/… (browser WebSocket URL)
+The same can be set with "cdp_url" in ~/.omatic/fetch.json.
+
+When no debug browser is running, fetch launches one over a private
+debugging pipe (--remote-debugging-pipe) and closes it afterwards, so no CDP
+port is left open for other local processes to take over. Use
+"fetch browser start" or --launch-mode port (or "launch_mode": "port") to
+keep a browser running on its debug port between runs. Windows, and Windows
+browsers launched from WSL, always use port mode.
+
+Inside WSL, fetch launches the Windows browser and connects through a netsh
+portproxy on the debug port + 10000 (e.g. 19222 for Edge) at the Windows host
+IP. Run "fetch browser status" to diagnose a missing portproxy or firewall rule.
+
+WebDriver browsers start their driver on demand. Use --webdriver-url to use a
+driver or Selenium server that is already running instead.
+
+Use --reuse-tab (or "reuse_tab": true) to log in through a tab that is
+already open on the URL's site, keeping the app's state, instead of opening
+a new tab. The tab is left open afterwards.
+
+Browsers launched by the bmux CLI (~/.bmux/sessions.json) are reused: fetch
+attaches to a live bmux session of the selected browser, preferring the one
+bmux is attached to, and prunes sessions whose browser has exited. Use
+--bmux-session <name> on auth, token and inspect to pick a session.
+
+Use --browser-profile to log in with one of the profiles in the browser's
+debug user-data dir, e.g. --browser-profile Work. It takes effect when fetch
+launches the browser; "fetch browser profiles" lists them.
+
+More browsers can be added under "browsers" in ~/.omatic/fetch.json:
+  {"browsers": {"thorium": {"exe_path": "/usr/bin/thorium", "debug_port": 9230}}}
+Set "protocol": "webdriver" on an entry whose exe_path is a WebDriver server.`,
 	SilenceUsage:  true,
 	SilenceErrors: true,
 }
@@ -26,15 +83,85 @@ func Execute() error {
 
 func init() {
 	rootCmd.Version = "0.1.0"
-	rootCmd.PersistentFlags().StringVarP(&browserFlag, "browser", "b", "edge", "Browser to use (edge, chrome)")
+	rootCmd.PersistentFlags().StringVarP(&browserFlag, "browser", "b", "edge", "Browser to use (edge, chrome, brave, chromium, vivaldi, firefox, safari, or one from config)")
+	rootCmd.PersistentFlags().StringVar(&browserProfileFlag, "browser-profile", "", "Browser profile to log in with, by name or directory (see \"fetch browser profiles\")")
+	rootCmd.PersistentFlags().StringVar(&cdpURLFlag, "cdp-url", "", "Connect to this CDP endpoint (http://host:port or ws://...) instead of launching a browser")
+	rootCmd.PersistentFlags().StringVar(&webDriverURLFlag, "webdriver-url", "", "Use this running WebDriver server (e.g. http://localhost:4444) for WebDriver browsers")
+	rootCmd.PersistentFlags().StringVar(&launchModeFlag, "launch-mode", "", "How to launch the browser when none is running: pipe (default; no open debug port) or port")
+	rootCmd.PersistentFlags().BoolVar(&reuseTabFlag, "reuse-tab", false, "Log in through a tab already open on the URL's site instead of opening a new one")
+	rootCmd.PersistentFlags().DurationVar(&connectTimeoutFlag, "connect-timeout", 0, "Timeout for connecting to the browser (default 10s)")
 }
 
 // GetBrowserType returns the browser type from the flag
 func GetBrowserType() auth.BrowserType {
-	switch browserFlag {
-	case "chrome":
-		return auth.BrowserChrome
-	default:
-		return auth.BrowserEdge
+	return auth.BrowserType(strings.ToLower(browserFlag))
+}
+
+// addBmuxSessionFlag adds --bmux-session to a command that drives a browser
+func addBmuxSessionFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&bmuxSessionFlag, "bmux-session", "", "Attach to this bmux session (e.g. edge) instead of launching a browser")
+}
+
+// GetBrowserConfig returns the browser configuration with command-line
+// overrides applied on top of the registry and config file. It fails for a
+// --browser that is not in the registry, so only commands that drive a
+// browser read the config file. With --bmux-session and no explicit
+// --browser, the browser is the one the session runs.
+func GetBrowserConfig() (*auth.BrowserConfig, error) {
+	if bmuxSessionFlag != "" && !rootCmd.PersistentFlags().Changed("browser") {
+		browserType, err := auth.BmuxSessionBrowser(bmuxSessionFlag)
+		if err != nil {
+			return nil, err
+		}
+		browserFlag = string(browserType)
+	}
+
+	config, err := auth.GetBrowserConfig(GetBrowserType())
+	if err != nil {
+		return nil, err
+	}
+
+	if cdpURLFlag != "" {
+		config.CDPURL = cdpURLFlag
+	}
+	if launchModeFlag != "" {
+		mode, err := auth.ParseLaunchMode(launchModeFlag)
+		if err != nil {
+			return nil, err
+		}
+		config.LaunchMode = mode
+	}
+	if browserProfileFlag != "" {
+		if config.Protocol != auth.ProtocolCDP {
+			return nil, fmt.Errorf("--browser-profile is not supported for %s", config.Type)
+		}
+		dir, err := auth.ResolveProfile(config.UserDataDir, browserProfileFlag)
+		if err != nil {
+			return nil, err
+		}
+		config.ProfileDirectory = dir
+	}
+	if bmuxSessionFlag != "" {
+		config.BmuxSession = bmuxSessionFlag
+	}
+	if reuseTabFlag {
+		config.ReuseTab = true
+	}
+	if webDriverURLFlag != "" {
+		config.WebDriverURL = webDriverURLFlag
+	}
+	if connectTimeoutFlag > 0 {
+		config.ConnectTimeout = connectTimeoutFlag
+	}
+
+	return config, nil
+}
+
+// newClient creates a client that authenticates with the configured browser
+func newClient() (*client.Client, error) {
+	config, err := GetBrowserConfig()
+	if err != nil {
+		return nil, err
 	}
+	return client.NewClientWithBrowserConfig(config)
 }
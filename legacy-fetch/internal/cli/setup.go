@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/omaticsoftware/fetch/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setupAllFlag    bool
+	setupDryRunFlag bool
+)
+
+var setupCmd = &cobra.Command{
+	Use:   "setup",
+	Short: "Install launchers that start browsers ready for fetch",
+	Long: `Install a launcher that starts the browser with its debug port open and
+its debug profile, so the browser you use every day is one fetch can attach to.
+
+  Linux:   a .desktop entry in ~/.local/share/applications
+  macOS:   a .command script in ~/Applications
+  Windows: a "(Debug)" Start Menu shortcut (also from WSL)
+
+The launchers use the debug port, user-data dir and --browser-profile that
+fetch itself would use.
+
+A browser started from a launcher keeps its debug port open for as long as
+it runs, and any local process can connect to it and read its cookies and
+storage. Without a launcher, fetch launches the browser over a private
+debugging pipe instead (see "fetch --help"). The configuration is validated first; use --dry-run
+to see what would be written without changing anything.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configs, err := setupConfigs()
+		if err != nil {
+			return err
+		}
+
+		if setupDryRunFlag {
+			fmt.Println("[dry run - no changes will be made]")
+		}
+
+		problems := auth.ValidateSetup(configs)
+		for _, problem := range problems {
+			fmt.Printf("Problem: %s\n", problem)
+		}
+		if len(problems) > 0 && !setupDryRunFlag {
+			return fmt.Errorf("configuration has %d problem(s); fix them or use --dry-run to review", len(problems))
+		}
+
+		for _, config := range configs {
+			launcher, err := auth.PlanLauncher(config)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n--- %s ---\n", config.DisplayName)
+			if setupDryRunFlag {
+				fmt.Printf("Would write: %s\n", launcher.Path)
+				fmt.Printf("  Command:   %s\n", launcher.CommandLine())
+				continue
+			}
+
+			if err := auth.WriteLauncher(launcher); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote: %s\n", launcher.Path)
+			fmt.Printf("  Command: %s\n", launcher.CommandLine())
+		}
+
+		fmt.Println("\nWarning: a browser started from these launchers keeps its debug port open while it runs;")
+		fmt.Println("any local process can then drive it and read its cookies. Close it when you no longer need fetch.")
+		if !setupDryRunFlag {
+			fmt.Println("\nStart the browser from the new launcher, then check it with: fetch browser status")
+		}
+		return nil
+	},
+}
+
+// setupConfigs returns the browsers to set up: the --browser one, or with
+// --all every installed CDP browser in the registry
+func setupConfigs() ([]*auth.BrowserConfig, error) {
+	if !setupAllFlag {
+		config, err := GetBrowserConfig()
+		if err != nil {
+			return nil, err
+		}
+		return []*auth.BrowserConfig{config}, nil
+	}
+
+	registry, err := auth.LoadBrowserRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*auth.BrowserConfig
+	for _, name := range registry.Names() {
+		config, err := auth.GetBrowserConfig(auth.BrowserType(name))
+		if err != nil {
+			return nil, err
+		}
+		if config.Protocol != auth.ProtocolCDP || !config.ExeFound {
+			continue
+		}
+		configs = append(configs, config)
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no installed browsers found")
+	}
+	return configs, nil
+}
+
+func init() {
+	rootCmd.AddCommand(setupCmd)
+	setupCmd.Flags().BoolVar(&setupAllFlag, "all", false, "Set up every installed browser instead of only --browser")
+	setupCmd.Flags().BoolVar(&setupDryRunFlag, "dry-run", false, "Show what would be written without changing anything")
+}
@@ -1,52 +1,303 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
 
 	"github.com/omaticsoftware/fetch/internal/auth"
 	"github.com/omaticsoftware/fetch/internal/client"
 	"github.com/spf13/cobra"
 )
 
+var (
+	storageOriginFlag string
+	freshTokenFlag    bool
+	audienceFlag      string
+	scopeFlag         string
+	listTokensFlag    bool
+	tenantFlag        string
+	providerFlag      string
+	tokenFormatFlag   string
+)
+
 var tokenCmd = &cobra.Command{
 	Use:   "token <url>",
 	Short: "Authenticate and print captured credentials",
 	Long: `Opens a browser to the specified URL, completes the login flow,
 then prints all captured credentials (JWT from localStorage, cookies) to stdout.
+Progress messages and warnings go to stderr, so stdout holds only the
+credentials in the --format chosen:
+  text      JWT=<token> and COOKIE=name=value; name2=value2 lines (default)
+  json      {"jwt": ..., "cookie_header": ..., "cookies": [{"name": ...}]}
+  env       export FETCH_JWT=... and FETCH_COOKIE=... for a POSIX shell
+  dotenv    FETCH_JWT=... and FETCH_COOKIE=... lines for a .env file
+  header    Authorization: Bearer <token>
+  curl      header = "..." lines for curl --config
+  netscape  the cookies as a Netscape cookies.txt file
+
+The JWT is looked for in the storage of the page the login ends on. If the
+app keeps it on another origin, such as its identity provider or an
+iframe, name that origin with --storage-origin to read its localStorage,
+sessionStorage and Firebase IndexedDB instead:
+  fetch token https://app.example.com --storage-origin auth.example.com
+
+Auth0 tokens and Firebase users are stored with the host's session. While
+the token is valid it is printed without opening the browser; once it
+expires, the stored refresh token is exchanged at the Auth0 tenant's
+/oauth/token endpoint (rotating refresh tokens) or Firebase's securetoken
+endpoint instead. Use --fresh to always log in through the browser.
 
-Output format (one per line):
-  JWT=<token>
-  COOKIE=name=value; name2=value2
+An app that calls several APIs caches one Auth0 token per audience and
+scope. Pick one with --audience and --scope (the token must include every
+listed scope), or show them all with --list, as text or with --format json:
+  fetch token https://app.example.com --list
+  fetch token https://app.example.com --audience https://api.example.com --scope "read:orders"
+
+Token providers are tried in order until one finds its tokens:
+  auth0    Auth0 SPA SDK cache in localStorage
+  msal     MSAL.js cache in sessionStorage or localStorage; --audience names
+           the resource (e.g. https://graph.microsoft.com or api://<app id>)
+           and --tenant the Azure AD tenant
+  cognito  AWS Cognito (Amplify) keys in localStorage; --audience names the
+           app client ID
+  firebase Firebase Auth user in IndexedDB (firebaseLocalStorageDb) or
+           storage; --audience names the API key or project ID
+  jwt      any JWT-shaped value in the Authorization headers the app sent,
+           its storage or its cookies
+Use --provider to read only one of them.
 
 Use in scripts:
-  TOKEN=$(fetch token https://app.example.com | grep ^JWT= | cut -d= -f2-)
-  curl -H "Authorization: Bearer $TOKEN" https://api.example.com/...`,
+  eval "$(fetch token https://app.example.com --format env)"
+  curl -H "Authorization: Bearer $FETCH_JWT" https://api.example.com/...
+
+  fetch token https://app.example.com --format curl > auth.curl
+  curl --config auth.curl https://api.example.com/...
+
+  fetch token https://app.example.com --format netscape > cookies.txt
+  curl -b cookies.txt https://app.example.com/...`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		targetURL := args[0]
+		if !validTokenFormat(tokenFormatFlag) {
+			return fmt.Errorf("unknown format %q (available: %s)", tokenFormatFlag, strings.Join(auth.TokenFormats, ", "))
+		}
+		if listTokensFlag && tokenFormatFlag != "text" && tokenFormatFlag != "json" {
+			return fmt.Errorf("--list prints text or json, not %s", tokenFormatFlag)
+		}
+
+		config, err := GetBrowserConfig()
+		if err != nil {
+			return err
+		}
+		if storageOriginFlag != "" {
+			if config.StorageOrigin, err = auth.NormalizeOrigin(storageOriginFlag); err != nil {
+				return err
+			}
+		}
 
-		c, err := client.NewClientWithBrowser(GetBrowserType())
+		c, err := client.NewClientWithBrowserConfig(config)
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
 		}
+		// Progress goes to stderr so stdout holds only the credentials
+		progress, stdout := cmd.ErrOrStderr(), cmd.OutOrStdout()
+		c.SetProgress(progress)
+
+		parsedURL, err := parseURL(targetURL)
+		if err != nil {
+			return err
+		}
+		host := parsedURL.Host
+		query := auth.TokenQuery{Audience: audienceFlag, Scope: scopeFlag, Tenant: tenantFlag}
+		extractors := auth.NewTokenExtractors()
+		if providerFlag != "" {
+			if _, err := extractors.Lookup(providerFlag); err != nil {
+				return err
+			}
+		}
+
+		// Stored tokens are Auth0's and Firebase's, the providers fetch can refresh
+		if !freshTokenFlag && !listTokensFlag && tenantFlag == "" {
+			if jwt, ok := storedToken(c, host); ok {
+				cookies, _ := c.LoadCookies(host)
+				return printTokenOutput(stdout, jwt, cookies)
+			}
+		}
 
 		result, err := c.AuthenticateAndCapture(targetURL)
 		if err != nil {
 			return fmt.Errorf("authentication failed: %w", err)
 		}
 
-		// Try to extract Auth0 JWT from localStorage
-		jwt, _ := auth.ParseAuth0Token(result.LocalStorage)
+		if listTokensFlag {
+			return printTokens(stdout, extractors, result)
+		}
 
-		output := auth.FormatTokenOutput(jwt, result.Cookies)
-		if output != "" {
-			fmt.Println(output)
+		jwt, err := selectToken(progress, c, extractors, host, result, query)
+		if err != nil {
+			return err
 		}
 
-		return nil
+		return printTokenOutput(stdout, jwt, result.Cookies)
 	},
 }
 
+// validTokenFormat reports whether format is one of auth.TokenFormats
+func validTokenFormat(format string) bool {
+	for _, f := range auth.TokenFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// printTokenOutput writes the credentials to w in the --format chosen
+func printTokenOutput(w io.Writer, jwt string, cookies []*http.Cookie) error {
+	output, err := auth.FormatToken(tokenFormatFlag, jwt, cookies)
+	if err != nil {
+		return err
+	}
+	if output != "" {
+		fmt.Fprintln(w, output)
+	}
+	return nil
+}
+
+// storedToken returns a token stored with the host's session by an earlier
+// run, if --provider allows its provider
+func storedToken(c *client.Client, host string) (string, bool) {
+	if providerFlag == "" || providerFlag == "auth0" {
+		if jwt, ok := c.StoredToken(host, auth.Auth0Selector{Audience: audienceFlag, Scope: scopeFlag}); ok {
+			return jwt, true
+		}
+	}
+	if (providerFlag == "" || providerFlag == "firebase") && scopeFlag == "" {
+		return c.StoredFirebaseToken(host, audienceFlag)
+	}
+	return "", false
+}
+
+// selectToken picks the access token to print with the token extractors.
+// An expired Auth0 or Firebase token is refreshed first, and the tokens are
+// kept with the session so later runs can skip the browser. An expired
+// Auth0 token without a refresh token is an error. Without selectors or
+// --provider a capture without tokens is not an error, and only the cookies
+// are printed. Progress is written to w.
+func selectToken(w io.Writer, c *client.Client, extractors *auth.TokenExtractors, host string, result *auth.AuthResult, query auth.TokenQuery) (string, error) {
+	token, err := extractors.Extract(result, query, providerFlag)
+	if errors.Is(err, auth.ErrNoToken) && providerFlag == "" && query == (auth.TokenQuery{}) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(w, "Using %s token from %s\n", token.Provider, token.Source)
+	if token.Expired(0) && token.Firebase == nil && token.Auth0 == nil {
+		fmt.Fprintf(w, "Warning: the token expired at %s\n", token.ExpiresAt.Format(time.RFC3339))
+	}
+	if tokens := token.Auth0; tokens != nil {
+		if tokens.Expired(0) {
+			if !tokens.CanRefresh() {
+				return "", fmt.Errorf("the Auth0 access token expired at %s and there is no refresh token to renew it", token.ExpiresAt.Format(time.RFC3339))
+			}
+			fmt.Fprintln(w, "Refreshing the Auth0 access token...")
+			refreshed, err := c.RefreshAuth0Tokens(tokens)
+			if err != nil {
+				return "", err
+			}
+			tokens = refreshed
+		}
+		if err := c.SaveSession(host, result.Cookies, tokens); err != nil {
+			fmt.Fprintf(w, "Warning: could not store tokens: %v\n", err)
+		}
+		return tokens.AccessToken, nil
+	}
+	if user := token.Firebase; user != nil {
+		if user.Expired(0) && user.CanRefresh() {
+			fmt.Fprintln(w, "Refreshing the Firebase ID token...")
+			refreshed, err := c.RefreshFirebaseUser(user)
+			if err != nil {
+				return "", err
+			}
+			user = refreshed
+		}
+		if err := c.SaveFirebaseSession(host, result.Cookies, user); err != nil {
+			fmt.Fprintf(w, "Warning: could not store tokens: %v\n", err)
+		}
+		return user.IDToken, nil
+	}
+	return token.Token, nil
+}
+
+// printTokens lists every token the extractors find in a capture, and the
+// entries that could not be read, to w as text or, with --format json, json
+func printTokens(w io.Writer, extractors *auth.TokenExtractors, result *auth.AuthResult) error {
+	tokens, problems, err := extractors.List(result, providerFlag)
+	if err != nil {
+		return err
+	}
+	if tokenFormatFlag == "json" {
+		output, err := auth.FormatTokenListJSON(tokens, problems)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, output)
+		return nil
+	}
+	if len(tokens) == 0 && len(problems) == 0 {
+		fmt.Fprintln(w, "No tokens found.")
+		return nil
+	}
+
+	fmt.Fprintln(w, "Tokens:")
+	for _, token := range tokens {
+		var details []string
+		if token.Audience != "" {
+			details = append(details, "audience "+token.Audience)
+		}
+		if token.Scope != "" {
+			details = append(details, fmt.Sprintf("scope %q", token.Scope))
+		}
+		if token.Tenant != "" {
+			details = append(details, "tenant "+token.Tenant)
+		}
+		if token.Subject != "" {
+			details = append(details, "user "+token.Subject)
+		}
+		switch {
+		case token.ExpiresAt.IsZero():
+			details = append(details, "no expiry")
+		case token.Expired(0):
+			details = append(details, "expired "+token.ExpiresAt.Format(time.RFC3339))
+		default:
+			details = append(details, "expires "+token.ExpiresAt.Format(time.RFC3339))
+		}
+		if token.Auth0 != nil && token.Auth0.RefreshToken != "" {
+			details = append(details, "refreshable")
+		}
+		fmt.Fprintf(w, "  - [%s] %s (%s)\n", token.Provider, token.Source, strings.Join(details, ", "))
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(w, "  Skipped: %v\n", problem)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(tokenCmd)
+	addBmuxSessionFlag(tokenCmd)
+	tokenCmd.Flags().BoolVar(&freshTokenFlag, "fresh", false, "Log in through the browser even if a stored token is valid or refreshable")
+	tokenCmd.Flags().StringVar(&audienceFlag, "audience", "", "Print the token issued for this API audience (Auth0) or resource (MSAL)")
+	tokenCmd.Flags().StringVar(&scopeFlag, "scope", "", "Print a token that includes these space-separated scopes")
+	tokenCmd.Flags().StringVar(&providerFlag, "provider", "", "Read the token of only this provider: auth0, msal, cognito, firebase or jwt")
+	tokenCmd.Flags().StringVar(&tenantFlag, "tenant", "", "Print the MSAL token issued in this Azure AD tenant ID")
+	tokenCmd.Flags().BoolVar(&listTokensFlag, "list", false, "List every token found instead of printing one")
+	tokenCmd.Flags().StringVar(&tokenFormatFlag, "format", "text", "Output format: "+strings.Join(auth.TokenFormats, ", "))
+	tokenCmd.Flags().StringVar(&storageOriginFlag, "storage-origin", "", "Read the storage of this origin (e.g. auth.example.com) instead of the page the login ends on")
 }
@@ -6,7 +6,9 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/omaticsoftware/fetch/internal/auth"
 )
@@ -16,6 +18,7 @@ type Client struct {
 	httpClient     *http.Client
 	sessionManager *auth.SessionManager
 	browserAuth    *auth.BrowserAuth
+	progress       io.Writer // Receives progress messages; os.Stdout when nil
 }
 
 // NewClient creates a new Client with default settings
@@ -40,11 +43,38 @@ func NewClientWithBrowser(browserType auth.BrowserType) (*Client, error) {
 	}, nil
 }
 
+// NewClientWithBrowserConfig creates a new Client that authenticates with an
+// explicit browser configuration (e.g. one pointing at a remote CDP endpoint)
+func NewClientWithBrowserConfig(config *auth.BrowserConfig) (*Client, error) {
+	c, err := NewClientWithBrowser(config.Type)
+	if err != nil {
+		return nil, err
+	}
+	c.browserAuth.SetBrowserConfig(config)
+	return c, nil
+}
+
 // SetBrowserType changes the browser used for authentication
 func (c *Client) SetBrowserType(browserType auth.BrowserType) {
 	c.browserAuth.SetBrowserType(browserType)
 }
 
+// SetProgress sends the progress messages of the client and its browser
+// logins to w instead of stdout
+func (c *Client) SetProgress(w io.Writer) {
+	c.progress = w
+	c.browserAuth.SetProgress(w)
+}
+
+// progressf prints a progress message to the client's progress writer
+func (c *Client) progressf(format string, args ...interface{}) {
+	w := c.progress
+	if w == nil {
+		w = os.Stdout
+	}
+	fmt.Fprintf(w, format, args...)
+}
+
 // Get performs a GET request with automatic cookie injection
 func (c *Client) Get(targetURL string) (*http.Response, error) {
 	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
@@ -152,7 +182,7 @@ func (c *Client) GetWithAuth(targetURL string) (*http.Response, error) {
 
 	// If no session exists, trigger authentication
 	if len(cookies) == 0 {
-		fmt.Printf("No session found for %s, triggering authentication...\n", host)
+		c.progressf("No session found for %s, triggering authentication...\n", host)
 		if err := c.browserAuth.Authenticate(targetURL); err != nil {
 			return nil, fmt.Errorf("authentication failed: %w", err)
 		}
@@ -171,10 +201,10 @@ func (c *Client) GetWithAuth(targetURL string) (*http.Response, error) {
 
 	// If we get 401, session may have expired - trigger re-authentication
 	if resp.StatusCode == http.StatusUnauthorized {
-		fmt.Printf("Session expired for %s, re-authenticating...\n", host)
+		c.progressf("Session expired for %s, re-authenticating...\n", host)
 		resp.Body.Close() // Close the 401 response
 
-		if err := c.browserAuth.Authenticate(targetURL); err != nil {
+		if err := c.Reauthenticate(targetURL); err != nil {
 			return nil, fmt.Errorf("re-authentication failed: %w", err)
 		}
 
@@ -207,7 +237,7 @@ func (c *Client) PostWithAuth(targetURL string, contentType string, body []byte)
 
 	// If no session exists, trigger authentication
 	if len(cookies) == 0 {
-		fmt.Printf("No session found for %s, triggering authentication...\n", host)
+		c.progressf("No session found for %s, triggering authentication...\n", host)
 		if err := c.browserAuth.Authenticate(targetURL); err != nil {
 			return nil, fmt.Errorf("authentication failed: %w", err)
 		}
@@ -230,10 +260,10 @@ func (c *Client) PostWithAuth(targetURL string, contentType string, body []byte)
 
 	// If we get 401, session may have expired - trigger re-authentication and retry
 	if resp.StatusCode == http.StatusUnauthorized {
-		fmt.Printf("Session expired for %s, re-authenticating...\n", host)
+		c.progressf("Session expired for %s, re-authenticating...\n", host)
 		resp.Body.Close()
 
-		if err := c.browserAuth.Authenticate(targetURL); err != nil {
+		if err := c.Reauthenticate(targetURL); err != nil {
 			return nil, fmt.Errorf("re-authentication failed: %w", err)
 		}
 
@@ -253,17 +283,155 @@ func (c *Client) PostWithAuth(targetURL string, contentType string, body []byte)
 	return resp, nil
 }
 
+// Reauthenticate renews an expired session. It first logs in silently,
+// which succeeds without interaction while the browser profile's SSO cookies
+// are valid, and opens the interactive login only when that fails, e.g.
+// because the identity provider asks for credentials.
+func (c *Client) Reauthenticate(targetURL string) error {
+	c.progressf("Trying silent re-authentication...\n")
+	err := c.browserAuth.SilentAuthenticate(targetURL)
+	if err == nil {
+		return nil
+	}
+
+	c.progressf("Silent re-authentication failed: %v\n", err)
+	c.progressf("Falling back to interactive login...\n")
+	return c.browserAuth.Authenticate(targetURL)
+}
+
 // Authenticate triggers browser authentication for a specific URL
 func (c *Client) Authenticate(targetURL string) error {
 	return c.browserAuth.Authenticate(targetURL)
 }
 
+// AuthenticateAll triggers browser authentication for several URLs in one
+// browser session, caching each session as its login completes
+func (c *Client) AuthenticateAll(targetURLs []string) ([]*auth.AuthOutcome, error) {
+	return c.browserAuth.AuthenticateAll(targetURLs)
+}
+
 // AuthenticateAndCapture triggers browser authentication and returns all
 // captured credentials (cookies, localStorage) without caching them.
 func (c *Client) AuthenticateAndCapture(targetURL string) (*auth.AuthResult, error) {
 	return c.browserAuth.AuthenticateAndCapture(targetURL)
 }
 
+// tokenExpiryMargin renews stored access tokens this long before they expire
+const tokenExpiryMargin = time.Minute
+
+// StoredToken returns an access token for host from the Auth0 tokens stored
+// with its session, without a browser. An expired token is renewed with the
+// refresh token, and the rotated tokens are stored. ok is false when there
+// is no usable token, or the stored one does not match selector, so the
+// caller should log in through the browser.
+func (c *Client) StoredToken(host string, selector auth.Auth0Selector) (token string, ok bool) {
+	tokens, err := c.sessionManager.LoadTokens(host)
+	if err != nil || tokens == nil || !selector.Matches(tokens) {
+		return "", false
+	}
+	if !tokens.Expired(tokenExpiryMargin) {
+		return tokens.AccessToken, true
+	}
+	if !tokens.CanRefresh() {
+		return "", false
+	}
+
+	c.progressf("Access token for %s has expired, refreshing...\n", host)
+	refreshed, err := auth.RefreshAuth0Tokens(c.httpClient, tokens)
+	if err != nil {
+		c.progressf("Token refresh failed: %v\n", err)
+		return "", false
+	}
+	if err := c.sessionManager.SaveTokens(host, refreshed); err != nil {
+		// The old refresh token is spent; the next run needs the browser
+		c.progressf("Warning: could not store refreshed tokens: %v\n", err)
+	}
+	return refreshed.AccessToken, true
+}
+
+// SessionToken returns the token stored with host's session as it is,
+// without refreshing it: the Auth0 access token, or else the Firebase ID
+// token
+func (c *Client) SessionToken(host string) (string, error) {
+	tokens, err := c.sessionManager.LoadTokens(host)
+	if err != nil {
+		return "", err
+	}
+	if tokens != nil {
+		return tokens.AccessToken, nil
+	}
+
+	user, err := c.sessionManager.LoadFirebaseUser(host)
+	if err != nil {
+		return "", err
+	}
+	if user != nil {
+		return user.IDToken, nil
+	}
+	return "", fmt.Errorf("no Auth0 or Firebase token stored for %s; run \"fetch token\" first, or pipe its output in for other providers", host)
+}
+
+// StoredFirebaseToken returns a Firebase ID token for host from the user
+// stored with its session, refreshing it through securetoken when it has
+// expired, like StoredToken. app, when set, is the API key or project ID
+// the user must belong to.
+func (c *Client) StoredFirebaseToken(host, app string) (token string, ok bool) {
+	user, err := c.sessionManager.LoadFirebaseUser(host)
+	if err != nil || user == nil || (app != "" && app != user.APIKey && app != user.ProjectID()) {
+		return "", false
+	}
+	if !user.Expired(tokenExpiryMargin) {
+		return user.IDToken, true
+	}
+
+	c.progressf("Firebase ID token for %s has expired, refreshing...\n", host)
+	refreshed, err := c.RefreshFirebaseUser(user)
+	if err != nil {
+		c.progressf("Token refresh failed: %v\n", err)
+		return "", false
+	}
+	if err := c.sessionManager.SaveFirebaseUser(host, refreshed); err != nil {
+		c.progressf("Warning: could not store refreshed tokens: %v\n", err)
+	}
+	return refreshed.IDToken, true
+}
+
+// RefreshAuth0Tokens renews an Auth0 access token with its refresh token
+func (c *Client) RefreshAuth0Tokens(tokens *auth.Auth0Tokens) (*auth.Auth0Tokens, error) {
+	return auth.RefreshAuth0Tokens(c.httpClient, tokens)
+}
+
+// RefreshFirebaseUser renews a Firebase user's ID token through securetoken
+func (c *Client) RefreshFirebaseUser(user *auth.FirebaseUser) (*auth.FirebaseUser, error) {
+	return auth.RefreshFirebaseUser(c.httpClient, user)
+}
+
+// SaveFirebaseSession stores the cookies and Firebase user captured for a
+// host, so later runs can skip the browser
+func (c *Client) SaveFirebaseSession(host string, cookies []*http.Cookie, user *auth.FirebaseUser) error {
+	if len(cookies) > 0 {
+		if err := c.sessionManager.SaveCookies(host, cookies); err != nil {
+			return err
+		}
+	}
+	return c.sessionManager.SaveFirebaseUser(host, user)
+}
+
+// SaveSession caches the cookies and Auth0 tokens captured for host
+func (c *Client) SaveSession(host string, cookies []*http.Cookie, tokens *auth.Auth0Tokens) error {
+	if len(cookies) > 0 {
+		if err := c.sessionManager.SaveCookies(host, cookies); err != nil {
+			return err
+		}
+	}
+	return c.sessionManager.SaveTokens(host, tokens)
+}
+
+// LoadCookies returns the cookies cached for host
+func (c *Client) LoadCookies(host string) ([]*http.Cookie, error) {
+	return c.sessionManager.LoadCookies(host)
+}
+
 // ListSessions returns a list of all cached sessions
 func (c *Client) ListSessions() ([]string, error) {
 	return c.sessionManager.ListSessions()
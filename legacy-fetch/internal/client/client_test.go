@@ -5,6 +5,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/omaticsoftware/fetch/internal/auth"
 )
@@ -225,3 +226,58 @@ func TestClient_DELETE(t *testing.T) {
 		t.Errorf("Expected status 204, got %d", resp.StatusCode)
 	}
 }
+
+// TestClient_StoredTokenRefreshes verifies that an expired stored Auth0 token
+// is refreshed at the token endpoint and the rotated tokens are stored
+func TestClient_StoredTokenRefreshes(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.Form
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"fresh-token","refresh_token":"refresh-2","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	sessionManager, err := auth.NewSessionManager()
+	if err != nil {
+		t.Fatalf("Failed to create session manager: %v", err)
+	}
+
+	host := "stored-token.test"
+	expired := &auth.Auth0Tokens{
+		AccessToken:  "stale-token",
+		RefreshToken: "refresh-1",
+		ClientID:     "client123",
+		ExpiresAt:    time.Now().Add(-time.Hour),
+		TokenURL:     server.URL,
+	}
+	if err := sessionManager.SaveTokens(host, expired); err != nil {
+		t.Fatalf("Failed to save test tokens: %v", err)
+	}
+	defer sessionManager.Clear(host)
+
+	client, err := NewClient()
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+
+	token, ok := client.StoredToken(host, auth.Auth0Selector{})
+	if !ok || token != "fresh-token" {
+		t.Fatalf("StoredToken() = %q, %v; want the refreshed token", token, ok)
+	}
+	if form.Get("refresh_token") != "refresh-1" || form.Get("client_id") != "client123" {
+		t.Errorf("unexpected refresh request %v", form)
+	}
+
+	stored, err := sessionManager.LoadTokens(host)
+	if err != nil || stored == nil || stored.RefreshToken != "refresh-2" {
+		t.Errorf("expected the rotated refresh token to be stored, got %+v, %v", stored, err)
+	}
+
+	// The refreshed token is now valid and served without another request
+	form = nil
+	if token, ok := client.StoredToken(host, auth.Auth0Selector{}); !ok || token != "fresh-token" || form != nil {
+		t.Errorf("expected the stored token without a refresh, got %q, %v", token, ok)
+	}
+}